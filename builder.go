@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/iMohamedSheta/xqb/dialects"
@@ -20,6 +22,16 @@ type QueryBuilderSettings struct {
 	onBeforeQueryCallback func(qb *QueryBuilder)
 	onAfterQueryCallback  func(query *QueryExecuted)
 	onAfterQueryExecution func(ctx context.Context)
+	interceptors          []Interceptor
+	onQueryExecuted       []func(execution *QueryExecution)
+	slowQueryThreshold    time.Duration
+	slowQueryLogger       *slog.Logger
+	slowQueryExplain      time.Duration
+	slowQueryExplains     atomic.Int32
+	tracer                Tracer
+	cacheStore            CacheStore
+	cacheCalls            cacheGroup
+	cacheEpochs           cacheEpochs
 }
 
 func NewQueryBuilderSettings() *QueryBuilderSettings {
@@ -76,6 +88,7 @@ func (s *QueryBuilderSettings) GetOnAfterQueryExecution() func(ctx context.Conte
 
 // QueryBuilder structure with all possible SELECT components
 type QueryBuilder struct {
+	manager         *DBM
 	connection      string
 	settings        *QueryBuilderSettings
 	dialect         dialects.DialectInterface
@@ -95,6 +108,9 @@ type QueryBuilder struct {
 	withCTEs        []*types.CTE
 	isUsingDistinct bool
 	tx              *sql.Tx
+	withoutTx       bool
+	usePrimary      bool
+	cache           *queryCache
 	errors          []error
 	deleteFrom      []string
 	options         map[types.Option]any // field for flexible Sql extensions
@@ -112,7 +128,15 @@ func (qb *QueryBuilder) GetSettings() *QueryBuilderSettings {
 	if qb.settings != nil {
 		return qb.settings
 	}
-	return DefaultSettings()
+	return qb.getManager().GetSettings()
+}
+
+// getManager returns the manager the builder was created from, the default manager for zero builders
+func (qb *QueryBuilder) getManager() *DBM {
+	if qb.manager != nil {
+		return qb.manager
+	}
+	return DBManager()
 }
 
 func (qb *QueryBuilder) GetConnection() string {
@@ -145,7 +169,13 @@ type QueryExecuted struct {
 
 // New creates a new QueryBuilder instance
 func New() *QueryBuilder {
+	return newQueryBuilder(DBManager())
+}
+
+// newQueryBuilder creates a QueryBuilder on the default connection of the manager
+func newQueryBuilder(manager *DBM) *QueryBuilder {
 	qb := &QueryBuilder{
+		manager:         manager,
 		queryType:       enums.SELECT,
 		columns:         []any{},
 		where:           nil,
@@ -164,7 +194,7 @@ func New() *QueryBuilder {
 		errors:          nil,
 		deleteFrom:      nil,
 		options:         make(map[types.Option]any),
-		settings:        DefaultSettings(),
+		settings:        manager.GetSettings(),
 		table:           nil,
 		insertedValues:  nil,
 		updatedBindings: nil,
@@ -172,7 +202,7 @@ func New() *QueryBuilder {
 	}
 
 	// Get the dialect name from the database connection
-	defaultConnection, err := DBManager().GetDefaultConnection()
+	defaultConnection, err := manager.GetDefaultConnection()
 	if err != nil {
 		qb.appendError(err)
 		return qb
@@ -213,12 +243,13 @@ func (qb *QueryBuilder) Table(table string) *QueryBuilder {
 // Reset resets the QueryBuilder instance
 func (qb *QueryBuilder) Reset() {
 	qb.errors = nil
-	defaultConnection, err := DBManager().GetDefaultConnection()
+	defaultConnection, err := qb.getManager().GetDefaultConnection()
 	if err != nil {
 		qb.appendError(err)
+	} else {
+		qb.connection = defaultConnection.Name
+		qb.dialect = dialects.GetDialect(defaultConnection.Dialect.MappedDialect())
 	}
-	qb.connection = defaultConnection.Name
-	qb.dialect = dialects.GetDialect(defaultConnection.Dialect.MappedDialect())
 	qb.queryType = enums.SELECT
 	qb.table = nil
 	qb.columns = nil
@@ -236,8 +267,11 @@ func (qb *QueryBuilder) Reset() {
 	qb.isUsingDistinct = false
 	qb.deleteFrom = nil
 	qb.tx = nil
+	qb.withoutTx = false
+	qb.usePrimary = false
+	qb.cache = nil
 	qb.options = make(map[types.Option]any)
-	qb.settings = DefaultSettings()
+	qb.settings = qb.getManager().GetSettings()
 	qb.insertedValues = nil
 	qb.updatedBindings = nil
 	qb.allowDangerous = false
@@ -396,9 +430,32 @@ func (qb *QueryBuilder) GetContext() context.Context {
 
 func (qb *QueryBuilder) WithTx(tx *sql.Tx) *QueryBuilder {
 	qb.tx = tx
+	qb.withoutTx = false
 	return qb
 }
 
+// WithoutTx runs the query outside the transaction propagated through the context (see ContextWithTx)
+func (qb *QueryBuilder) WithoutTx() *QueryBuilder {
+	qb.tx = nil
+	qb.withoutTx = true
+	return qb
+}
+
+// UsePrimary runs the query on the primary even if it's a read the connection replicas could serve
+func (qb *QueryBuilder) UsePrimary() *QueryBuilder {
+	qb.usePrimary = true
+	return qb
+}
+
+// activeTx returns the transaction the query runs in, set with WithTx or propagated through the context
+func (qb *QueryBuilder) activeTx() *sql.Tx {
+	if qb.tx != nil || qb.withoutTx {
+		return qb.tx
+	}
+	tx, _ := txFromContext(qb.ctx, qb.getManager(), qb.connection)
+	return tx
+}
+
 func (qb *QueryBuilder) Connection(connection string) *QueryBuilder {
 	qb.connection = connection
 	return qb
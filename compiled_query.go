@@ -0,0 +1,184 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/iMohamedSheta/xqb/shared/enums"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
+)
+
+// Parameter is a placeholder binding of a compiled query, its value is given on every execution (see Compile).
+// A Parameter is a single value, it isn't expanded like a slice given to WhereIn.
+type Parameter struct {
+	Name string
+}
+
+// Param returns a named placeholder binding for Compile
+func Param(name string) Parameter {
+	return Parameter{Name: name}
+}
+
+// CompiledQuery is an immutable query compiled once by Compile and executed with new parameter values,
+// it is safe for concurrent use.
+type CompiledQuery struct {
+	sql        string
+	bindings   []any    // fixed values and Parameter placeholders
+	params     []string // distinct parameter names in the order of their first binding
+	manager    *DBM
+	settings   *QueryBuilderSettings
+	connection string
+	queryType  enums.QueryType
+	table      string
+	locked     bool
+	usePrimary bool
+}
+
+// Compile builds the query once and returns a CompiledQuery executed with the values of its Param placeholders,
+// given by position (in the order of their first binding) or by name with sql.Named.
+// The transaction is taken from the context of the execution (see ContextWithTx), not from WithTx.
+//
+// Example:
+//
+//	byEmail, err := xqb.Table("users").Where("email", "=", xqb.Param("email")).Where("active", "=", true).Compile()
+//	users, err := byEmail.Get(ctx, "a@example.com")
+//	users, err = byEmail.Get(ctx, sql.Named("email", "b@example.com"))
+func (qb *QueryBuilder) Compile() (*CompiledQuery, error) {
+	query, bindings, err := qb.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%w: Compile() Failed to build the sql query, %v", xqbErr.ErrInvalidQuery, err)
+	}
+
+	cq := &CompiledQuery{
+		sql:        query,
+		bindings:   append([]any(nil), bindings...),
+		manager:    qb.getManager(),
+		settings:   qb.GetSettings(),
+		connection: qb.connection,
+		queryType:  qb.queryType,
+		usePrimary: qb.usePrimary,
+	}
+	if qb.table != nil {
+		cq.table = qb.table.Name
+	}
+	_, cq.locked = qb.GetOption(types.OptionLock)
+
+	seen := make(map[string]bool)
+	for _, binding := range cq.bindings {
+		if param, ok := binding.(Parameter); ok && !seen[param.Name] {
+			seen[param.Name] = true
+			cq.params = append(cq.params, param.Name)
+		}
+	}
+
+	return cq, nil
+}
+
+// Sql returns the compiled sql
+func (cq *CompiledQuery) Sql() string {
+	return cq.sql
+}
+
+// Params returns the parameter names in positional order
+func (cq *CompiledQuery) Params() []string {
+	return append([]string(nil), cq.params...)
+}
+
+// Bindings returns the bindings of an execution with the parameter values given by position or by name (sql.Named)
+func (cq *CompiledQuery) Bindings(args ...any) ([]any, error) {
+	values := make(map[string]any, len(cq.params))
+	positional := 0
+	for _, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			if _, exists := values[named.Name]; exists {
+				return nil, fmt.Errorf("%w: CompiledQuery parameter %q is given more than once", xqbErr.ErrInvalidQuery, named.Name)
+			}
+			values[named.Name] = named.Value
+			continue
+		}
+
+		if positional >= len(cq.params) {
+			return nil, fmt.Errorf("%w: CompiledQuery expects %d parameter(s), got more", xqbErr.ErrInvalidQuery, len(cq.params))
+		}
+		name := cq.params[positional]
+		if _, exists := values[name]; exists {
+			return nil, fmt.Errorf("%w: CompiledQuery parameter %q is given more than once", xqbErr.ErrInvalidQuery, name)
+		}
+		values[name] = arg
+		positional++
+	}
+
+	for _, name := range cq.params {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("%w: CompiledQuery missing value of parameter %q", xqbErr.ErrInvalidQuery, name)
+		}
+	}
+	if len(values) != len(cq.params) {
+		return nil, fmt.Errorf("%w: CompiledQuery unknown named parameter, expects %v", xqbErr.ErrInvalidQuery, cq.params)
+	}
+
+	bindings := make([]any, len(cq.bindings))
+	for i, binding := range cq.bindings {
+		if param, ok := binding.(Parameter); ok {
+			bindings[i] = values[param.Name]
+		} else {
+			bindings[i] = binding
+		}
+	}
+
+	return bindings, nil
+}
+
+// sqlQuery creates the SqlQuery of an execution with the bound parameter values
+func (cq *CompiledQuery) sqlQuery(ctx context.Context, args []any) (*SqlQuery, error) {
+	bindings, err := cq.Bindings(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := cq.manager.Sql(cq.sql, bindings...).
+		WithContext(ctx).
+		WithSettings(cq.settings).
+		WithAfterExec(cq.settings.GetOnAfterQueryExecution()).
+		Connection(cq.connection)
+	s.queryType = cq.queryType
+	s.table = cq.table
+	s.locked = cq.locked
+	if cq.usePrimary {
+		s.UsePrimary()
+	}
+
+	return s, nil
+}
+
+// Get executes the compiled query and returns all results
+func (cq *CompiledQuery) Get(ctx context.Context, args ...any) ([]map[string]any, error) {
+	s, err := cq.sqlQuery(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return queryAll(s, "CompiledQuery.Get()")
+}
+
+// First executes the compiled query and returns the first row, compile it with Limit(1) to fetch a single row
+func (cq *CompiledQuery) First(ctx context.Context, args ...any) (map[string]any, error) {
+	results, err := cq.Get(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, xqbErr.ErrNotFound
+	}
+	return results[0], nil
+}
+
+// Query executes the compiled query and returns the rows
+func (cq *CompiledQuery) Query(ctx context.Context, args ...any) (*sql.Rows, error) {
+	s, err := cq.sqlQuery(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query()
+}
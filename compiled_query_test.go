@@ -0,0 +1,141 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordArgs returns a handler answering users rows and recording the arguments of every statement
+func recordArgs(mu *sync.Mutex, args *[][]any) fakeHandler {
+	return func(query string, values []any) (*fakeResult, error) {
+		mu.Lock()
+		*args = append(*args, values)
+		mu.Unlock()
+		return usersRows(1), nil
+	}
+}
+
+func Test_CompiledQuery_PositionalAndNamedBindings(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		args [][]any
+	)
+	fdb := newFakeConnection(t, xqb.DialectMySql, recordArgs(&mu, &args))
+
+	cq, err := xqb.Table("users").Connection(fdb.name).
+		Where("email", "=", xqb.Param("email")).
+		Where("active", "=", true).
+		Where("age", ">", xqb.Param("age")).
+		Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM `users` WHERE `email` = ? AND `active` = ? AND `age` > ?", cq.Sql())
+	assert.Equal(t, []string{"email", "age"}, cq.Params())
+
+	users, err := cq.Get(context.Background(), "a@example.com", 18)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	_, err = cq.Get(context.Background(), sql.Named("age", 30), sql.Named("email", "b@example.com"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, [][]any{
+		{"a@example.com", true, int64(18)},
+		{"b@example.com", true, int64(30)},
+	}, args)
+	assert.Equal(t, []string{cq.Sql(), cq.Sql()}, fdb.Statements())
+}
+
+func Test_CompiledQuery_ReusedParameterPostgres(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		args [][]any
+	)
+	fdb := newFakeConnection(t, xqb.DialectPostgres, recordArgs(&mu, &args))
+
+	cq, err := xqb.Table("posts").Connection(fdb.name).SetDialect(types.DialectPostgres).
+		Where("author_id", "=", xqb.Param("user")).
+		OrWhere("editor_id", "=", xqb.Param("user")).
+		Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "posts" WHERE "author_id" = $1 OR "editor_id" = $2`, cq.Sql())
+
+	_, err = cq.Get(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{int64(7), int64(7)}}, args)
+}
+
+func Test_CompiledQuery_BindingErrors(t *testing.T) {
+	cq, err := xqb.Table("users").Where("id", "=", xqb.Param("id")).Where("role", "=", xqb.Param("role")).Compile()
+	assert.NoError(t, err)
+
+	for name, args := range map[string][]any{
+		"missing":   {1},
+		"too many":  {1, "admin", 2},
+		"unknown":   {sql.Named("id", 1), sql.Named("name", "x")},
+		"duplicate": {1, sql.Named("id", 2)},
+	} {
+		_, err := cq.Bindings(args...)
+		assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery, name)
+	}
+
+	bindings, err := cq.Bindings(1, sql.Named("role", "admin"))
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1, "admin"}, bindings)
+
+	_, err = cq.Get(context.Background())
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery)
+}
+
+func Test_CompiledQuery_TransactionFromContext(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+
+	cq, err := xqb.Table("users").Connection(fdb.name).Where("id", "=", xqb.Param("id")).Compile()
+	assert.NoError(t, err)
+
+	err = xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cq.First(ctx, 1)
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SELECT * FROM `users` WHERE `id` = ?"}, fdb.TxStatements())
+}
+
+func Test_CompiledQuery_ConcurrentUse(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		args [][]any
+	)
+	fdb := newFakeConnection(t, xqb.DialectMySql, recordArgs(&mu, &args))
+
+	cq, err := xqb.Table("users").Connection(fdb.name).Where("id", "=", xqb.Param("id")).Compile()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cq.Get(context.Background(), i)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, values := range args {
+		seen[fmt.Sprint(values...)] = true
+	}
+	assert.Len(t, seen, 20)
+	assert.Equal(t, 0, fdb.OpenRows())
+}
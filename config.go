@@ -0,0 +1,434 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a connection opened by Open, the zero pool fields keep the database/sql defaults
+type Config struct {
+	Name             string           `json:"name" yaml:"name"` // "default" when empty
+	Driver           string           `json:"driver" yaml:"driver"`
+	DSN              string           `json:"dsn" yaml:"dsn"`
+	Dialect          Dialect          `json:"dialect" yaml:"dialect"` // inferred from the driver when empty
+	MaxOpen          int              `json:"max_open" yaml:"max_open"`
+	MaxIdle          int              `json:"max_idle" yaml:"max_idle"`
+	ConnMaxLifetime  time.Duration    `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime  time.Duration    `json:"conn_max_idle_time" yaml:"conn_max_idle_time"`
+	Replicas         []string         `json:"replicas" yaml:"replicas"` // DSNs of the read replicas, opened with the same driver and pool
+	ReplicaSelection ReplicaSelection `json:"replica_selection" yaml:"replica_selection"`
+	Sticky           bool             `json:"sticky" yaml:"sticky"`
+	OnConnect        []string         `json:"on_connect" yaml:"on_connect"` // statements run on every new connection (SET time_zone...)
+
+	StatementCacheSize int `json:"statement_cache_size" yaml:"statement_cache_size"` // see Connection.StatementCacheSize
+}
+
+// Open opens, pings and registers the connections in the default manager (see DBM.Open)
+func Open(configs ...Config) error {
+	return DBManager().Open(configs...)
+}
+
+// Open opens, pings and registers the connections of the configs in the manager.
+// Nothing is registered when a connection fails to open, the connections opened so far are closed.
+// The names must be unique and not registered yet, close a connection before opening it again.
+//
+// Example:
+//
+//	err := xqb.Open(xqb.Config{
+//		Driver:    "postgres",
+//		DSN:       "postgres://app@localhost/app",
+//		MaxOpen:   20,
+//		Replicas:  []string{"postgres://app@replica/app"},
+//		OnConnect: []string{"SET search_path TO app"},
+//	})
+func (m *DBM) Open(configs ...Config) error {
+	return m.OpenContext(context.Background(), configs...)
+}
+
+// OpenContext is Open with a context bounding the pings
+func (m *DBM) OpenContext(ctx context.Context, configs ...Config) error {
+	if len(configs) == 0 {
+		return fmt.Errorf("%w: Open() no connection config", xqbErr.ErrNoConnection)
+	}
+
+	// checked before opening the databases, addConnections checks the names again when registering them
+	names := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		name := configName(config)
+		if names[name] || m.HasConnection(name) {
+			return fmt.Errorf("%w: Open() connection %q is already registered", xqbErr.ErrInvalidConfig, name)
+		}
+		names[name] = true
+	}
+
+	conns := make([]*Connection, 0, len(configs))
+	closeAll := func() {
+		for _, conn := range conns {
+			_ = conn.DB.Close()
+			_ = conn.closeReplicas()
+		}
+	}
+
+	for _, config := range configs {
+		conn, err := openConnection(ctx, config)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		conns = append(conns, conn)
+	}
+
+	if err := m.addConnections(conns); err != nil {
+		closeAll()
+		return err
+	}
+
+	return nil
+}
+
+// configName returns the name of the connection of the config, "default" when empty
+func configName(config Config) string {
+	if config.Name == "" {
+		return "default"
+	}
+	return config.Name
+}
+
+// openConnection opens and pings the primary and the replicas of the config
+func openConnection(ctx context.Context, config Config) (*Connection, error) {
+	config.Name = configName(config)
+	if config.Driver == "" || config.DSN == "" {
+		return nil, fmt.Errorf("%w: Open() connection %q requires a driver and a dsn", xqbErr.ErrInvalidConfig, config.Name)
+	}
+
+	dialect := config.Dialect
+	if dialect == "" {
+		dialect = dialectOfDriver(config.Driver)
+		if dialect == "" {
+			return nil, fmt.Errorf("%w: Open() unknown dialect of driver %q for connection %q, set the Dialect", xqbErr.ErrUnsupportedFeature, config.Driver, config.Name)
+		}
+	}
+
+	conn := &Connection{
+		Name:               config.Name,
+		Dialect:            dialect,
+		ReplicaSelection:   config.ReplicaSelection,
+		Sticky:             config.Sticky,
+		StatementCacheSize: config.StatementCacheSize,
+	}
+
+	var err error
+	conn.DB, err = openDB(ctx, config, config.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Open() connection %q: %w", xqbErr.ErrNoConnection, config.Name, err)
+	}
+
+	for i, dsn := range config.Replicas {
+		replica, err := openDB(ctx, config, dsn)
+		if err != nil {
+			_ = conn.DB.Close()
+			_ = conn.closeReplicas()
+			return nil, fmt.Errorf("%w: Open() replica %d of connection %q: %w", xqbErr.ErrNoConnection, i, config.Name, err)
+		}
+		conn.Replicas = append(conn.Replicas, replica)
+	}
+
+	return conn, nil
+}
+
+// openDB opens the database of the dsn with the driver, pool and on connect statements of the config then pings it
+func openDB(ctx context.Context, config Config, dsn string) (*sql.DB, error) {
+	connector, err := newConnector(config.Driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.OnConnect) > 0 {
+		connector = onConnectConnector{Connector: connector, statements: config.OnConnect}
+	}
+
+	db := sql.OpenDB(connector)
+	if config.MaxOpen > 0 {
+		db.SetMaxOpenConns(config.MaxOpen)
+	}
+	if config.MaxIdle > 0 {
+		db.SetMaxIdleConns(config.MaxIdle)
+	}
+	if config.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// newConnector returns the connector of the registered driver for the dsn
+func newConnector(driverName, dsn string) (driver.Connector, error) {
+	// sql.Open only looks the driver up, it doesn't connect
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	_ = db.Close()
+
+	if driverCtx, ok := drv.(driver.DriverContext); ok {
+		return driverCtx.OpenConnector(dsn)
+	}
+	return dsnConnector{driver: drv, dsn: dsn}, nil
+}
+
+// dsnConnector is the connector of the drivers not implementing driver.DriverContext
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// onConnectConnector runs the on connect statements on every new connection
+type onConnectConnector struct {
+	driver.Connector
+	statements []string
+}
+
+func (c onConnectConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range c.statements {
+		if err := execOnConn(ctx, conn, statement); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("on connect statement %q failed: %w", statement, err)
+		}
+	}
+
+	return conn, nil
+}
+
+// Close closes the wrapped connector when it's an io.Closer, sql.DB.Close calls it
+func (c onConnectConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// execOnConn executes a statement without arguments on a driver connection
+func execOnConn(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if stmtCtx, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = stmtCtx.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// dialectOfDriver returns the dialect of the well known drivers
+func dialectOfDriver(driverName string) Dialect {
+	switch strings.ToLower(driverName) {
+	case "mysql":
+		return DialectMySql
+	case "postgres", "postgresql", "pgx", "pq":
+		return DialectPostgres
+	default:
+		return ""
+	}
+}
+
+// ConfigFromEnv reads a connection config from the environment variables with the given prefix ("DB" when empty):
+// PREFIX_NAME, PREFIX_DRIVER, PREFIX_DSN, PREFIX_DIALECT, PREFIX_MAX_OPEN, PREFIX_MAX_IDLE, PREFIX_CONN_MAX_LIFETIME,
+// PREFIX_CONN_MAX_IDLE_TIME (durations like "5m"), PREFIX_REPLICAS (comma separated DSNs), PREFIX_STICKY,
+// PREFIX_STATEMENT_CACHE_SIZE and PREFIX_ON_CONNECT (a statement or a JSON array of statements).
+// The statements aren't split on ";" which may be part of a literal.
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix == "" {
+		prefix = "DB"
+	}
+	env := func(key string) string {
+		return strings.TrimSpace(os.Getenv(prefix + "_" + key))
+	}
+
+	config := Config{
+		Name:    env("NAME"),
+		Driver:  env("DRIVER"),
+		DSN:     env("DSN"),
+		Dialect: Dialect(env("DIALECT")),
+	}
+
+	var errs []error
+	parseInt := func(key string, dest *int) {
+		if value := env(key); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_%s: %w", prefix, key, err))
+			}
+			*dest = n
+		}
+	}
+	parseDuration := func(key string, dest *time.Duration) {
+		if value := env(key); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_%s: %w", prefix, key, err))
+			}
+			*dest = d
+		}
+	}
+
+	parseInt("MAX_OPEN", &config.MaxOpen)
+	parseInt("MAX_IDLE", &config.MaxIdle)
+	parseInt("STATEMENT_CACHE_SIZE", &config.StatementCacheSize)
+	parseDuration("CONN_MAX_LIFETIME", &config.ConnMaxLifetime)
+	parseDuration("CONN_MAX_IDLE_TIME", &config.ConnMaxIdleTime)
+
+	if value := env("STICKY"); value != "" {
+		sticky, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s_STICKY: %w", prefix, err))
+		}
+		config.Sticky = sticky
+	}
+	config.Replicas = splitNonEmpty(env("REPLICAS"), ",")
+	if value := env("ON_CONNECT"); strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &config.OnConnect); err != nil {
+			errs = append(errs, fmt.Errorf("%s_ON_CONNECT: %w", prefix, err))
+		}
+	} else if value != "" {
+		config.OnConnect = []string{value}
+	}
+
+	if len(errs) > 0 {
+		return Config{}, fmt.Errorf("%w: ConfigFromEnv() %w", xqbErr.ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	return config, nil
+}
+
+// splitNonEmpty splits the value and drops the empty parts
+func splitNonEmpty(value, sep string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// configFile is the layout of the config files
+type configFile struct {
+	Connections []Config `json:"connections" yaml:"connections"`
+}
+
+// LoadConfigFile reads the connection configs of a JSON (.json) or YAML (.yaml, .yml) file:
+//
+//	connections:
+//	  - name: default
+//	    driver: mysql
+//	    dsn: app:secret@tcp(localhost:3306)/app
+//	    max_open: 20
+//	    conn_max_lifetime: 5m
+//	    on_connect: ["SET time_zone = '+00:00'"]
+func LoadConfigFile(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file configFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		return nil, fmt.Errorf("%w: LoadConfigFile() unsupported config file %q, use .json, .yaml or .yml", xqbErr.ErrUnsupportedFeature, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: LoadConfigFile() failed to parse %q: %w", xqbErr.ErrInvalidConfig, path, err)
+	}
+
+	return file.Connections, nil
+}
+
+// UnmarshalJSON decodes a config, the durations are strings like "5m" or nanoseconds
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	var raw struct {
+		plain
+		ConnMaxLifetime json.RawMessage `json:"conn_max_lifetime"`
+		ConnMaxIdleTime json.RawMessage `json:"conn_max_idle_time"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = Config(raw.plain)
+	var err error
+	if c.ConnMaxLifetime, err = jsonDuration(raw.ConnMaxLifetime); err != nil {
+		return fmt.Errorf("conn_max_lifetime: %w", err)
+	}
+	if c.ConnMaxIdleTime, err = jsonDuration(raw.ConnMaxIdleTime); err != nil {
+		return fmt.Errorf("conn_max_idle_time: %w", err)
+	}
+	return nil
+}
+
+// jsonDuration decodes a duration string or a number of nanoseconds
+func jsonDuration(data json.RawMessage) (time.Duration, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return 0, nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		return time.ParseDuration(value)
+	}
+
+	var nanoseconds int64
+	if err := json.Unmarshal(data, &nanoseconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(nanoseconds), nil
+}
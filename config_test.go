@@ -0,0 +1,294 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDSNs maps the DSNs of the "xqbfake" driver to their fake databases
+var fakeDSNs = struct {
+	sync.Mutex
+	dbs map[string]*fakeDB
+}{dbs: make(map[string]*fakeDB)}
+
+// fakeConnectors holds the connectors opened by the "xqbfakeconnector" driver by DSN
+var fakeConnectors sync.Map
+
+func init() {
+	sql.Register("xqbfake", fakeDSNDriver{})
+	sql.Register("xqbfakeconnector", fakeConnectorDriver{})
+}
+
+// fakeDSNDriver is a registered driver opening the fake database of the DSN
+type fakeDSNDriver struct{}
+
+func (fakeDSNDriver) Open(dsn string) (driver.Conn, error) {
+	fakeDSNs.Lock()
+	defer fakeDSNs.Unlock()
+
+	fdb, ok := fakeDSNs.dbs[dsn]
+	if !ok {
+		return nil, errors.New("unknown dsn " + dsn)
+	}
+	return &fakeConn{db: fdb}, nil
+}
+
+// fakeConnectorDriver is fakeDSNDriver opening closable connectors
+type fakeConnectorDriver struct {
+	fakeDSNDriver
+}
+
+func (d fakeConnectorDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	connector := &fakeConnector{driver: d, dsn: dsn}
+	fakeConnectors.Store(dsn, connector)
+	return connector, nil
+}
+
+type fakeConnector struct {
+	driver driver.Driver
+	dsn    string
+	closed atomic.Bool
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c *fakeConnector) Driver() driver.Driver                        { return c.driver }
+func (c *fakeConnector) Close() error                                 { c.closed.Store(true); return nil }
+
+// newFakeDSN registers a fake database opened by the "xqbfake" driver with the DSN
+func newFakeDSN(t *testing.T, dsn string) *fakeDB {
+	t.Helper()
+
+	fdb := &fakeDB{name: dsn}
+	fakeDSNs.Lock()
+	fakeDSNs.dbs[dsn] = fdb
+	fakeDSNs.Unlock()
+
+	t.Cleanup(func() {
+		fakeDSNs.Lock()
+		delete(fakeDSNs.dbs, dsn)
+		fakeDSNs.Unlock()
+	})
+
+	return fdb
+}
+
+func Test_Open_RegistersConnectionsWithReplicas(t *testing.T) {
+	primary := newFakeDSN(t, t.Name()+"_primary")
+	replica := newFakeDSN(t, t.Name()+"_replica")
+	mgr := xqb.NewManager()
+	t.Cleanup(func() { _ = mgr.CloseAllConnections() })
+
+	err := mgr.Open(xqb.Config{
+		Driver:          "xqbfake",
+		DSN:             primary.name,
+		Dialect:         xqb.DialectPostgres,
+		MaxOpen:         5,
+		MaxIdle:         2,
+		ConnMaxLifetime: time.Minute,
+		Replicas:        []string{replica.name},
+		OnConnect:       []string{"SET search_path TO app", "SET TIME ZONE 'UTC'"},
+	})
+	assert.NoError(t, err)
+
+	conn, err := mgr.Connection("default")
+	assert.NoError(t, err)
+	assert.Equal(t, xqb.DialectPostgres, conn.Dialect)
+	assert.Equal(t, 5, conn.DB.Stats().MaxOpenConnections)
+	assert.Len(t, conn.Replicas, 1)
+
+	_, err = mgr.Table("users").Get()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"SET search_path TO app", "SET TIME ZONE 'UTC'"}, primary.Statements())
+	assert.Equal(t, []string{"SET search_path TO app", "SET TIME ZONE 'UTC'", `SELECT * FROM "users"`}, replica.Statements())
+}
+
+func Test_Open_FailsWithoutRegistering(t *testing.T) {
+	primary := newFakeDSN(t, t.Name()+"_primary")
+	mgr := xqb.NewManager()
+
+	err := mgr.Open(
+		xqb.Config{Name: "main", Driver: "xqbfake", DSN: primary.name, Dialect: xqb.DialectMySql},
+		xqb.Config{Name: "broken", Driver: "xqbfake", DSN: "missing", Dialect: xqb.DialectMySql},
+	)
+
+	assert.ErrorIs(t, err, xqbErr.ErrNoConnection)
+	assert.ErrorContains(t, err, "unknown dsn missing")
+	assert.False(t, mgr.HasConnection("main"))
+
+	err = mgr.Open(xqb.Config{Driver: "xqbfake", DSN: primary.name})
+	assert.ErrorIs(t, err, xqbErr.ErrUnsupportedFeature, "the dialect of an unknown driver must be set")
+
+	err = mgr.Open(xqb.Config{Driver: "unregistered", DSN: primary.name, Dialect: xqb.DialectMySql})
+	assert.Error(t, err)
+}
+
+func Test_Open_RejectsRegisteredNames(t *testing.T) {
+	primary := newFakeDSN(t, t.Name()+"_primary")
+	mgr := xqb.NewManager()
+	t.Cleanup(func() { _ = mgr.CloseAllConnections() })
+
+	err := mgr.Open(
+		xqb.Config{Driver: "xqbfake", DSN: primary.name, Dialect: xqb.DialectMySql},
+		xqb.Config{Name: "default", Driver: "xqbfake", DSN: primary.name, Dialect: xqb.DialectMySql},
+	)
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidConfig)
+	assert.False(t, mgr.HasConnection("default"))
+
+	assert.NoError(t, mgr.Open(xqb.Config{Driver: "xqbfake", DSN: primary.name, Dialect: xqb.DialectMySql}))
+	conn, err := mgr.Connection("default")
+	assert.NoError(t, err)
+
+	// the registered pool is kept open
+	err = mgr.Open(xqb.Config{Driver: "xqbfake", DSN: primary.name, Dialect: xqb.DialectMySql})
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidConfig)
+	assert.NoError(t, conn.DB.Ping())
+}
+
+func Test_Open_ConcurrentOpensRegisterOnce(t *testing.T) {
+	primary := newFakeDSN(t, t.Name()+"_primary")
+	mgr := xqb.NewManager()
+	t.Cleanup(func() { _ = mgr.CloseAllConnections() })
+
+	var (
+		wg       sync.WaitGroup
+		opened   atomic.Int32
+		rejected atomic.Int32
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := mgr.Open(xqb.Config{Driver: "xqbfake", DSN: primary.name, Dialect: xqb.DialectMySql})
+			if err == nil {
+				opened.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, xqbErr.ErrInvalidConfig)
+			rejected.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, opened.Load())
+	assert.EqualValues(t, 9, rejected.Load())
+	assert.True(t, mgr.HasConnection("default"))
+}
+
+func Test_Open_OnConnectClosesTheDriverConnector(t *testing.T) {
+	primary := newFakeDSN(t, t.Name()+"_primary")
+	mgr := xqb.NewManager()
+
+	err := mgr.Open(xqb.Config{Driver: "xqbfakeconnector", DSN: primary.name, Dialect: xqb.DialectMySql, OnConnect: []string{"SET NAMES utf8mb4"}})
+	assert.NoError(t, err)
+
+	connector, ok := fakeConnectors.Load(primary.name)
+	assert.True(t, ok)
+	assert.False(t, connector.(*fakeConnector).closed.Load())
+
+	assert.NoError(t, mgr.CloseAllConnections())
+	assert.True(t, connector.(*fakeConnector).closed.Load())
+}
+
+func Test_Open_PingFailure(t *testing.T) {
+	primary := newFakeDSN(t, t.Name()+"_primary")
+	primary.SetPingError(errors.New("connection refused"))
+
+	err := xqb.NewManager().Open(xqb.Config{Driver: "xqbfake", DSN: primary.name, Dialect: xqb.DialectMySql})
+
+	assert.ErrorIs(t, err, xqbErr.ErrNoConnection)
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func Test_ConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_DB_DRIVER", "mysql")
+	t.Setenv("APP_DB_DSN", "app@tcp(localhost)/app")
+	t.Setenv("APP_DB_MAX_OPEN", "20")
+	t.Setenv("APP_DB_CONN_MAX_LIFETIME", "5m")
+	t.Setenv("APP_DB_REPLICAS", "app@tcp(r1)/app, app@tcp(r2)/app")
+	t.Setenv("APP_DB_STICKY", "true")
+	t.Setenv("APP_DB_ON_CONNECT", `["SET time_zone = '+00:00'", "SET @separator = ';'"]`)
+
+	config, err := xqb.ConfigFromEnv("APP_DB")
+
+	assert.NoError(t, err)
+	assert.Equal(t, xqb.Config{
+		Driver:          "mysql",
+		DSN:             "app@tcp(localhost)/app",
+		MaxOpen:         20,
+		ConnMaxLifetime: 5 * time.Minute,
+		Replicas:        []string{"app@tcp(r1)/app", "app@tcp(r2)/app"},
+		Sticky:          true,
+		OnConnect:       []string{"SET time_zone = '+00:00'", "SET @separator = ';'"},
+	}, config)
+
+	// a single statement isn't split
+	t.Setenv("APP_DB_ON_CONNECT", "SET @separator = ';'")
+	config, err = xqb.ConfigFromEnv("APP_DB")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SET @separator = ';'"}, config.OnConnect)
+
+	t.Setenv("APP_DB_MAX_IDLE", "many")
+	_, err = xqb.ConfigFromEnv("APP_DB")
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidConfig)
+	assert.ErrorContains(t, err, "APP_DB_MAX_IDLE")
+}
+
+func Test_LoadConfigFile(t *testing.T) {
+	expected := []xqb.Config{
+		{Name: "default", Driver: "postgres", DSN: "postgres://app@localhost/app", MaxOpen: 10, ConnMaxIdleTime: 30 * time.Second, OnConnect: []string{"SET search_path TO app"}},
+		{Name: "reports", Driver: "mysql", DSN: "app@tcp(localhost)/reports", Dialect: xqb.DialectMySql},
+	}
+
+	files := map[string]string{
+		"db.json": `{"connections": [
+			{"name": "default", "driver": "postgres", "dsn": "postgres://app@localhost/app", "max_open": 10, "conn_max_idle_time": "30s", "on_connect": ["SET search_path TO app"]},
+			{"name": "reports", "driver": "mysql", "dsn": "app@tcp(localhost)/reports", "dialect": "mysql"}
+		]}`,
+		"db.yaml": `connections:
+  - name: default
+    driver: postgres
+    dsn: postgres://app@localhost/app
+    max_open: 10
+    conn_max_idle_time: 30s
+    on_connect:
+      - SET search_path TO app
+  - name: reports
+    driver: mysql
+    dsn: app@tcp(localhost)/reports
+    dialect: mysql
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			configs, err := xqb.LoadConfigFile(path)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, configs)
+		})
+	}
+
+	_, err := xqb.LoadConfigFile(filepath.Join(t.TempDir(), "db.toml"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "db.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"connections": [{"max_open": "many"}]}`), 0o600))
+	_, err = xqb.LoadConfigFile(path)
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidConfig)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
 	"github.com/iMohamedSheta/xqb/shared/types"
@@ -23,31 +24,64 @@ func (d Dialect) MappedDialect() types.Dialect {
 
 type Connection struct {
 	Name    string
-	DB      *sql.DB
+	DB      *sql.DB // the primary receiving the writes, locked reads and transactions
 	Dialect Dialect
+
+	Replicas         []*sql.DB        // read replicas receiving the SELECTs without locks
+	ReplicaSelection ReplicaSelection // how the replica of a read is chosen, round robin by default
+	Sticky           bool             // keep the reads on the primary after a write in a sticky context (see ContextWithSticky)
+
+	StatementCacheSize int // prepared statements cached per database (LRU), 0 disables the cache
+
+	nextReplica   atomic.Uint64
+	health        connectionHealth
+	stmtCacheOnce sync.Once
+	stmtCache     *statementCache
 }
 
 type DBM struct {
 	mu                sync.RWMutex
 	defaultConnection string
 	connections       map[string]*Connection
+	settings          *QueryBuilderSettings
+	cacheNamespace    string // separates the cached results of the managers sharing a cache store
+
+	healthMu      sync.Mutex
+	healthChecker *healthChecker
 }
 
 var (
 	managerOnce     sync.Once
 	managerInstance *DBM
+	managerCount    atomic.Uint64
 )
 
+// DBManager returns the default manager used by the global helpers (Table, Sql, Transaction...)
 func DBManager() *DBM {
 	managerOnce.Do(func() {
-		managerInstance = &DBM{
-			defaultConnection: "default",
-			connections:       make(map[string]*Connection),
-		}
+		managerInstance = NewManager()
+		// the default manager keys its cached results like every process sharing the store
+		managerInstance.cacheNamespace = ""
 	})
 	return managerInstance
 }
 
+// NewManager creates a database manager independent from the default one, the builders, raw queries and
+// transactions created from it (mgr.Table, mgr.Sql, mgr.Transaction...) only use its connections.
+//
+// Example:
+//
+//	mgr := xqb.NewManager()
+//	mgr.SetConnection(&xqb.Connection{Name: "default", DB: db, Dialect: xqb.DialectPostgres})
+//	users, err := mgr.Table("users").Where("active", "=", true).Get()
+func NewManager() *DBM {
+	return &DBM{
+		defaultConnection: "default",
+		cacheNamespace:    fmt.Sprintf("manager-%d", managerCount.Add(1)),
+		connections:       make(map[string]*Connection),
+	}
+}
+
 func (m *DBM) GetConnections() map[string]*Connection {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -74,6 +108,22 @@ func (m *DBM) SetConnection(conn *Connection) error {
 	return nil
 }
 
+// addConnections registers the connections under one lock when none of their names is registered yet,
+// so concurrent Open calls can't replace each other's connections
+func (m *DBM) addConnections(conns []*Connection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, conn := range conns {
+		if existing, ok := m.connections[conn.Name]; ok && existing != nil && existing.DB != nil {
+			return fmt.Errorf("%w: Open() connection %q is already registered", xqbErr.ErrInvalidConfig, conn.Name)
+		}
+	}
+	for _, conn := range conns {
+		m.connections[conn.Name] = conn
+	}
+	return nil
+}
+
 func (m *DBM) Connection(name string) (*Connection, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -95,6 +145,19 @@ func (m *DBM) ConnectionDB(name string) (*sql.DB, error) {
 	return conn.DB, nil
 }
 
+// ReadDB returns the database serving the reads of the connection, one of its replicas or the primary without replicas
+func (m *DBM) ReadDB(name string) (*sql.DB, error) {
+	conn, err := m.Connection(name)
+	if err != nil {
+		return nil, err
+	}
+	db := conn.ReadDB()
+	if db == nil {
+		return nil, fmt.Errorf("%w: connection %q has no sql.DB", xqbErr.ErrNoConnection, name)
+	}
+	return db, nil
+}
+
 func (m *DBM) SetDialect(name string, dialect Dialect) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -149,25 +212,39 @@ func (m *DBM) CloseConnection(name string) error {
 	if !ok || conn == nil {
 		return fmt.Errorf("%w: connection %q not found", xqbErr.ErrNoConnection, name)
 	}
+	conn.closeStatements()
 	if conn.DB != nil {
 		if err := conn.DB.Close(); err != nil {
 			return err
 		}
 	}
+	if err := conn.closeReplicas(); err != nil {
+		return err
+	}
 	delete(m.connections, name)
 	return nil
 }
 
 func (m *DBM) CloseAllConnections() error {
+	m.StopHealthChecks()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	var errs []error
 	for name, conn := range m.connections {
+		if conn != nil {
+			conn.closeStatements()
+		}
 		if conn != nil && conn.DB != nil {
 			if err := conn.DB.Close(); err != nil {
 				errs = append(errs, fmt.Errorf("%s: %v", name, err))
 			}
 		}
+		if conn != nil {
+			if err := conn.closeReplicas(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			}
+		}
 	}
 	m.connections = make(map[string]*Connection)
 	if len(errs) > 0 {
@@ -0,0 +1,270 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// HealthCheckOptions configures the background health checker of the connections
+type HealthCheckOptions struct {
+	Interval         time.Duration // time between two checks, 10s by default
+	Timeout          time.Duration // timeout of a ping, 2s by default
+	FailureThreshold int           // consecutive failed pings taking a database out of rotation, 1 by default
+	SuccessThreshold int           // consecutive successful pings putting it back in rotation, 2 by default
+}
+
+// withDefaults fills the zero fields with the default options
+func (o HealthCheckOptions) withDefaults() HealthCheckOptions {
+	if o.Interval <= 0 {
+		o.Interval = 10 * time.Second
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 2 * time.Second
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = 1
+	}
+	if o.SuccessThreshold <= 0 {
+		o.SuccessThreshold = 2
+	}
+	return o
+}
+
+// DBHealth is the health of a primary or replica database
+type DBHealth struct {
+	Healthy              bool
+	LastCheck            time.Time // zero until the first check
+	LastError            error
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+}
+
+// ConnectionHealth is the health of the primary and the replicas (in the order of Connection.Replicas) of a connection
+type ConnectionHealth struct {
+	Primary  DBHealth
+	Replicas []DBHealth
+}
+
+// connectionHealth holds the health of the databases of a connection updated by the health checker
+type connectionHealth struct {
+	mu       sync.RWMutex
+	primary  DBHealth
+	replicas []DBHealth
+}
+
+// healthChecker is the running background health checker of the DBM
+type healthChecker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartHealthChecks starts a background health checker pinging the primary and the replicas of every connection
+// on an interval. An unhealthy replica is taken out of the read rotation and re-added after consecutive successful
+// pings, the reads fail over to the primary when all the replicas are unhealthy. A running checker is replaced,
+// it stops with StopHealthChecks or CloseAllConnections.
+//
+// Example:
+//
+//	xqb.DBManager().StartHealthChecks(xqb.HealthCheckOptions{Interval: 5 * time.Second})
+//	http.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+//		if !xqb.DBManager().IsHealthy("default") {
+//			w.WriteHeader(http.StatusServiceUnavailable)
+//		}
+//	})
+func (m *DBM) StartHealthChecks(opts HealthCheckOptions) {
+	m.StopHealthChecks()
+
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	checker := &healthChecker{cancel: cancel, done: make(chan struct{})}
+
+	m.healthMu.Lock()
+	m.healthChecker = checker
+	m.healthMu.Unlock()
+
+	go func() {
+		defer close(checker.done)
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			m.CheckHealth(ctx, opts)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// StopHealthChecks stops the background health checker and waits for the running check to end
+func (m *DBM) StopHealthChecks() {
+	m.healthMu.Lock()
+	checker := m.healthChecker
+	m.healthChecker = nil
+	m.healthMu.Unlock()
+
+	if checker != nil {
+		checker.cancel()
+		<-checker.done
+	}
+}
+
+// CheckHealth pings the primary and the replicas of every connection once and updates their health
+func (m *DBM) CheckHealth(ctx context.Context, opts HealthCheckOptions) {
+	opts = opts.withDefaults()
+
+	m.mu.RLock()
+	conns := make([]*Connection, 0, len(m.connections))
+	for _, conn := range m.connections {
+		if conn != nil {
+			conns = append(conns, conn)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, conn := range conns {
+		if ctx.Err() != nil {
+			return
+		}
+
+		primary := ping(ctx, conn.DB, opts.Timeout)
+		replicas := make([]error, len(conn.Replicas))
+		for i, replica := range conn.Replicas {
+			replicas[i] = ping(ctx, replica, opts.Timeout)
+		}
+
+		// a ping interrupted by StopHealthChecks says nothing about the database
+		if ctx.Err() != nil {
+			return
+		}
+
+		conn.health.update(primary, replicas, opts)
+	}
+}
+
+// ping pings the database with the timeout
+func ping(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	if db == nil {
+		return sql.ErrConnDone
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+// update records the result of the pings of the primary and the replicas
+func (h *connectionHealth) update(primary error, replicas []error, opts HealthCheckOptions) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.primary = h.primary.record(primary, now, opts)
+
+	// replicas added since the last check start healthy
+	for len(h.replicas) < len(replicas) {
+		h.replicas = append(h.replicas, DBHealth{Healthy: true})
+	}
+	h.replicas = h.replicas[:len(replicas)]
+	for i, err := range replicas {
+		h.replicas[i] = h.replicas[i].record(err, now, opts)
+	}
+}
+
+// record returns the health after a ping, it changes once the failure or success threshold is reached
+func (d DBHealth) record(err error, now time.Time, opts HealthCheckOptions) DBHealth {
+	if d.LastCheck.IsZero() {
+		d.Healthy = true
+	}
+	d.LastCheck = now
+	d.LastError = err
+
+	if err != nil {
+		d.ConsecutiveFailures++
+		d.ConsecutiveSuccesses = 0
+		if d.ConsecutiveFailures >= opts.FailureThreshold {
+			d.Healthy = false
+		}
+		return d
+	}
+
+	d.ConsecutiveSuccesses++
+	d.ConsecutiveFailures = 0
+	if !d.Healthy && d.ConsecutiveSuccesses >= opts.SuccessThreshold {
+		d.Healthy = true
+	}
+	return d
+}
+
+// snapshot returns the health of the connection, the databases not checked yet are reported healthy
+func (h *connectionHealth) snapshot(replicas int) ConnectionHealth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	health := ConnectionHealth{Primary: h.primary, Replicas: make([]DBHealth, replicas)}
+	if health.Primary.LastCheck.IsZero() {
+		health.Primary.Healthy = true
+	}
+	for i := range health.Replicas {
+		if i < len(h.replicas) {
+			health.Replicas[i] = h.replicas[i]
+		} else {
+			health.Replicas[i] = DBHealth{Healthy: true}
+		}
+	}
+	return health
+}
+
+// healthyReplicas returns the replicas in the read rotation
+func (c *Connection) healthyReplicas() []*sql.DB {
+	c.health.mu.RLock()
+	defer c.health.mu.RUnlock()
+
+	if len(c.health.replicas) == 0 {
+		return c.Replicas
+	}
+
+	healthy := make([]*sql.DB, 0, len(c.Replicas))
+	for i, replica := range c.Replicas {
+		if i >= len(c.health.replicas) || c.health.replicas[i].Healthy {
+			healthy = append(healthy, replica)
+		}
+	}
+	return healthy
+}
+
+// Health returns the health of every connection
+func (m *DBM) Health() map[string]ConnectionHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	health := make(map[string]ConnectionHealth, len(m.connections))
+	for name, conn := range m.connections {
+		if conn != nil {
+			health[name] = conn.health.snapshot(len(conn.Replicas))
+		}
+	}
+	return health
+}
+
+// ConnectionHealth returns the health of the connection
+func (m *DBM) ConnectionHealth(name string) (ConnectionHealth, error) {
+	conn, err := m.Connection(name)
+	if err != nil {
+		return ConnectionHealth{}, err
+	}
+	return conn.health.snapshot(len(conn.Replicas)), nil
+}
+
+// IsHealthy reports whether the primary of the connection is healthy, false for an unknown connection
+func (m *DBM) IsHealthy(name string) bool {
+	health, err := m.ConnectionHealth(name)
+	return err == nil && health.Primary.Healthy
+}
@@ -0,0 +1,93 @@
+package xqb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Health_UnhealthyReplicaLeavesRotation(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, nil)
+	replicas := newFakeReplicas(t, primary, 2, nil)
+	opts := xqb.HealthCheckOptions{FailureThreshold: 1, SuccessThreshold: 2}
+
+	replicas[0].SetPingError(errors.New("connection refused"))
+	xqb.DBManager().CheckHealth(context.Background(), opts)
+
+	health, err := xqb.DBManager().ConnectionHealth(primary.name)
+	assert.NoError(t, err)
+	assert.True(t, health.Primary.Healthy)
+	assert.False(t, health.Replicas[0].Healthy)
+	assert.EqualError(t, health.Replicas[0].LastError, "connection refused")
+	assert.True(t, health.Replicas[1].Healthy)
+
+	for range 3 {
+		_, err := xqb.Table("users").Connection(primary.name).Get()
+		assert.NoError(t, err)
+	}
+	assert.Empty(t, replicas[0].Statements())
+	assert.Len(t, replicas[1].Statements(), 3)
+
+	// back in rotation after two consecutive successes
+	replicas[0].SetPingError(nil)
+	xqb.DBManager().CheckHealth(context.Background(), opts)
+	health, _ = xqb.DBManager().ConnectionHealth(primary.name)
+	assert.False(t, health.Replicas[0].Healthy)
+
+	xqb.DBManager().CheckHealth(context.Background(), opts)
+	health, _ = xqb.DBManager().ConnectionHealth(primary.name)
+	assert.True(t, health.Replicas[0].Healthy)
+	assert.Equal(t, 2, health.Replicas[0].ConsecutiveSuccesses)
+}
+
+func Test_Health_FailoverToPrimary(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, nil)
+	replicas := newFakeReplicas(t, primary, 1, nil)
+
+	replicas[0].SetPingError(errors.New("down"))
+	xqb.DBManager().CheckHealth(context.Background(), xqb.HealthCheckOptions{})
+
+	_, err := xqb.Table("users").Connection(primary.name).Get()
+
+	assert.NoError(t, err)
+	assert.Empty(t, replicas[0].Statements())
+	assert.Equal(t, []string{"SELECT * FROM `users`"}, primary.Statements())
+}
+
+func Test_Health_PrimaryFailureThreshold(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectPostgres, nil)
+	opts := xqb.HealthCheckOptions{FailureThreshold: 2}
+
+	assert.True(t, xqb.DBManager().IsHealthy(primary.name), "not checked yet")
+
+	primary.SetPingError(errors.New("down"))
+	xqb.DBManager().CheckHealth(context.Background(), opts)
+	assert.True(t, xqb.DBManager().IsHealthy(primary.name))
+
+	xqb.DBManager().CheckHealth(context.Background(), opts)
+	assert.False(t, xqb.DBManager().IsHealthy(primary.name))
+	assert.False(t, xqb.DBManager().Health()[primary.name].Primary.Healthy)
+	assert.False(t, xqb.DBManager().IsHealthy("unknown"))
+}
+
+func Test_Health_BackgroundCheckerStopsOnCloseAll(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, nil)
+	primary.SetPingError(errors.New("down"))
+	// CloseAllConnections removes the default connection of the tests
+	t.Cleanup(Setup)
+
+	xqb.DBManager().StartHealthChecks(xqb.HealthCheckOptions{Interval: time.Millisecond})
+
+	assert.Eventually(t, func() bool {
+		return !xqb.DBManager().IsHealthy(primary.name)
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, xqb.DBManager().CloseAllConnections())
+	assert.Empty(t, xqb.DBManager().Health())
+	// stopping again is a no-op
+	xqb.DBManager().StopHealthChecks()
+}
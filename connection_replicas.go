@@ -0,0 +1,125 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ReplicaSelection is the strategy choosing the read replica of a query
+type ReplicaSelection int
+
+const (
+	ReplicaRoundRobin ReplicaSelection = iota
+	ReplicaRandom
+)
+
+// ReadDB returns a healthy replica chosen with the ReplicaSelection of the connection,
+// or the primary when it has no replicas or none of them is healthy (see StartHealthChecks)
+func (c *Connection) ReadDB() *sql.DB {
+	replicas := c.healthyReplicas()
+	if len(replicas) == 0 {
+		return c.DB
+	}
+
+	switch c.ReplicaSelection {
+	case ReplicaRandom:
+		return replicas[rand.N(len(replicas))]
+	default:
+		return replicas[(c.nextReplica.Add(1)-1)%uint64(len(replicas))]
+	}
+}
+
+// closeReplicas closes the read replicas of the connection
+func (c *Connection) closeReplicas() error {
+	var errs []error
+	for _, replica := range c.Replicas {
+		if replica != nil {
+			if err := replica.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stickyContextKey is the context key of the connections written during a request
+type stickyContextKey struct{}
+
+type stickyState struct {
+	mu    sync.Mutex
+	wrote map[string]bool
+}
+
+// ContextWithSticky returns a context recording the writes, the reads on a Sticky connection run on the primary
+// after the first write made with the context so a request reads its own writes despite the replication lag.
+//
+// Example:
+//
+//	func StickyMiddleware(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			next.ServeHTTP(w, r.WithContext(xqb.ContextWithSticky(r.Context())))
+//		})
+//	}
+func ContextWithSticky(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, stickyContextKey{}, &stickyState{wrote: make(map[string]bool)})
+}
+
+// markWrite records a write on the connection in the sticky context
+func markWrite(ctx context.Context, connection string) {
+	if ctx == nil {
+		return
+	}
+	if state, ok := ctx.Value(stickyContextKey{}).(*stickyState); ok {
+		state.mu.Lock()
+		state.wrote[connection] = true
+		state.mu.Unlock()
+	}
+}
+
+// wroteInContext reports whether the sticky context wrote on the connection
+func wroteInContext(ctx context.Context, connection string) bool {
+	if ctx == nil {
+		return false
+	}
+	state, ok := ctx.Value(stickyContextKey{}).(*stickyState)
+	if !ok {
+		return false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.wrote[connection]
+}
+
+// eventDB returns the database running the event, a read replica of the connection when the event allows it
+func (m *DBM) eventDB(ctx context.Context, event *QueryEvent) (*sql.DB, error) {
+	if !event.Replica {
+		return m.ConnectionDB(event.Connection)
+	}
+
+	conn, err := m.Connection(event.Connection)
+	if err != nil {
+		return nil, err
+	}
+	if conn.Sticky && wroteInContext(ctx, event.Connection) {
+		return m.ConnectionDB(event.Connection)
+	}
+
+	return m.ReadDB(event.Connection)
+}
+
+// lockingReadRegex matches the locking clauses of a raw SELECT
+var lockingReadRegex = regexp.MustCompile(`(?i)\bFOR\s+(UPDATE|SHARE|NO\s+KEY\s+UPDATE|KEY\s+SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b`)
+
+// isReplicaRead reports whether a raw statement is a SELECT without locks
+func isReplicaRead(query string) bool {
+	keyword, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return strings.EqualFold(keyword, "SELECT") && !lockingReadRegex.MatchString(query)
+}
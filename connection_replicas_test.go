@@ -0,0 +1,139 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeReplicas creates the fake replicas of the connection of the primary fake database
+func newFakeReplicas(t *testing.T, primary *fakeDB, count int, configure func(conn *xqb.Connection)) []*fakeDB {
+	t.Helper()
+
+	conn, err := xqb.GetConnection(primary.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replicas []*fakeDB
+	for i := range count {
+		replica := &fakeDB{
+			name: fmt.Sprintf("%s_replica_%d", primary.name, i),
+			handler: func(query string, args []any) (*fakeResult, error) {
+				return usersRows(1), nil
+			},
+		}
+		replicas = append(replicas, replica)
+		conn.Replicas = append(conn.Replicas, sql.OpenDB(replica))
+	}
+	if configure != nil {
+		configure(conn)
+	}
+
+	return replicas
+}
+
+func Test_Replicas_ReadsRoundRobin(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, nil)
+	replicas := newFakeReplicas(t, primary, 2, nil)
+
+	for range 4 {
+		_, err := xqb.Table("users").Connection(primary.name).Get()
+		assert.NoError(t, err)
+	}
+
+	assert.Empty(t, primary.Statements())
+	assert.Len(t, replicas[0].Statements(), 2)
+	assert.Len(t, replicas[1].Statements(), 2)
+}
+
+func Test_Replicas_RandomSelection(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, nil)
+	replicas := newFakeReplicas(t, primary, 2, func(conn *xqb.Connection) {
+		conn.ReplicaSelection = xqb.ReplicaRandom
+	})
+
+	for range 10 {
+		_, err := xqb.Sql("SELECT * FROM users").Connection(primary.name).Query()
+		assert.NoError(t, err)
+	}
+
+	assert.Empty(t, primary.Statements())
+	assert.Len(t, append(replicas[0].Statements(), replicas[1].Statements()...), 10)
+}
+
+func Test_Replicas_PrimaryStatements(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	replicas := newFakeReplicas(t, primary, 1, nil)
+
+	_, err := xqb.Table("users").Connection(primary.name).Where("id", "=", 1).Delete()
+	assert.NoError(t, err)
+	_, err = xqb.Table("users").Connection(primary.name).LockForUpdate().Get()
+	assert.NoError(t, err)
+	_, err = xqb.Table("users").Connection(primary.name).SharedLock().Get()
+	assert.NoError(t, err)
+	_, err = xqb.Table("users").Connection(primary.name).UsePrimary().Get()
+	assert.NoError(t, err)
+	_, err = xqb.Sql("SELECT * FROM users FOR UPDATE").Connection(primary.name).Query()
+	assert.NoError(t, err)
+	err = xqb.TransactionOn(primary.name, func(tx *sql.Tx) error {
+		_, err := xqb.Table("users").Connection(primary.name).WithTx(tx).Get()
+		return err
+	})
+	assert.NoError(t, err)
+
+	assert.Empty(t, replicas[0].Statements())
+	assert.Equal(t, []string{
+		"DELETE FROM `users` WHERE `id` = ?",
+		"SELECT * FROM `users` FOR UPDATE",
+		"SELECT * FROM `users` LOCK IN SHARE MODE",
+		"SELECT * FROM `users`",
+		"SELECT * FROM users FOR UPDATE",
+		"BEGIN",
+		"SELECT * FROM `users`",
+		"COMMIT",
+	}, primary.Statements())
+}
+
+func Test_Replicas_StickyAfterWrite(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	replicas := newFakeReplicas(t, primary, 1, func(conn *xqb.Connection) {
+		conn.Sticky = true
+	})
+
+	ctx := xqb.ContextWithSticky(context.Background())
+
+	_, err := xqb.Table("users").Connection(primary.name).WithContext(ctx).Get()
+	assert.NoError(t, err)
+	_, err = xqb.Table("users").Connection(primary.name).WithContext(ctx).Where("id", "=", 1).Update(map[string]any{"name": "bob"})
+	assert.NoError(t, err)
+	_, err = xqb.Table("users").Connection(primary.name).WithContext(ctx).Get()
+	assert.NoError(t, err)
+
+	// another request still reads from the replica
+	_, err = xqb.Table("users").Connection(primary.name).WithContext(xqb.ContextWithSticky(context.Background())).Get()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"SELECT * FROM `users`", "SELECT * FROM `users`"}, replicas[0].Statements())
+	assert.Equal(t, []string{"UPDATE `users` SET `name` = ? WHERE `id` = ?", "SELECT * FROM `users`"}, primary.Statements())
+}
+
+func Test_Replicas_CloseConnectionClosesReplicas(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, nil)
+	newFakeReplicas(t, primary, 1, nil)
+
+	conn, err := xqb.GetConnection(primary.name)
+	assert.NoError(t, err)
+	replica := conn.Replicas[0]
+
+	assert.NoError(t, xqb.Close(primary.name))
+	assert.Error(t, replica.Ping())
+}
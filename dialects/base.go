@@ -18,6 +18,11 @@ func GetDialect(dialect types.Dialect) DialectInterface {
 	}
 }
 
+// Dialects returns the supported dialects, a new dialect is added here and to GetDialect
+func Dialects() []types.Dialect {
+	return []types.Dialect{types.DialectMySql, types.DialectPostgres}
+}
+
 // DialectInterface defines the methods that all grammars must implement
 type DialectInterface interface {
 	Getdialect() types.Dialect
@@ -25,6 +30,10 @@ type DialectInterface interface {
 	CompileInsert(*types.QueryBuilderData) (string, []any, error)
 	CompileUpdate(*types.QueryBuilderData) (string, []any, error)
 	CompileDelete(*types.QueryBuilderData) (string, []any, error)
+	CompileTransactionOptions(*types.TxOptions) ([]string, error)
+	IsRetryableError(err error) bool
+	TranslateError(err error) error
+	CompileExplain(query string) string
 
 	Build(qb *types.QueryBuilderData) (string, []any, error)
 }
@@ -0,0 +1,109 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+)
+
+// MySql error numbers translated into the database error sentinels
+var errorKinds = map[int]error{
+	1062: xqbErr.ErrUniqueViolation,     // ER_DUP_ENTRY
+	1586: xqbErr.ErrUniqueViolation,     // ER_DUP_ENTRY_WITH_KEY_NAME
+	1216: xqbErr.ErrForeignKeyViolation, // ER_NO_REFERENCED_ROW
+	1217: xqbErr.ErrForeignKeyViolation, // ER_ROW_IS_REFERENCED
+	1451: xqbErr.ErrForeignKeyViolation, // ER_ROW_IS_REFERENCED_2
+	1452: xqbErr.ErrForeignKeyViolation, // ER_NO_REFERENCED_ROW_2
+	1048: xqbErr.ErrNotNullViolation,    // ER_BAD_NULL_ERROR
+	1364: xqbErr.ErrNotNullViolation,    // ER_NO_DEFAULT_FOR_FIELD
+	3819: xqbErr.ErrCheckViolation,      // ER_CHECK_CONSTRAINT_VIOLATED
+	1213: xqbErr.ErrDeadlock,            // ER_LOCK_DEADLOCK
+	1205: xqbErr.ErrLockTimeout,         // ER_LOCK_WAIT_TIMEOUT
+	3572: xqbErr.ErrLockTimeout,         // ER_LOCK_NOWAIT
+	2006: xqbErr.ErrConnectionLost,      // CR_SERVER_GONE_ERROR
+	2013: xqbErr.ErrConnectionLost,      // CR_SERVER_LOST
+}
+
+var (
+	// Duplicate entry 'john@example.com' for key 'users.users_email_unique'
+	duplicateKeyPattern = regexp.MustCompile("for key '([^']+)'")
+	// ... a foreign key constraint fails (`shop`.`posts`, CONSTRAINT `posts_user_id_fk` FOREIGN KEY (`user_id`) REFERENCES ...
+	foreignKeyPattern = regexp.MustCompile("fails \\((?:`[^`]+`\\.)?`([^`]+)`, CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`\\)")
+	// Column 'name' cannot be null / Field 'name' doesn't have a default value
+	columnPattern = regexp.MustCompile("(?:Column|Field) '([^']+)'")
+	// Check constraint 'users_age_check' is violated.
+	checkPattern = regexp.MustCompile("Check constraint '([^']+)'")
+)
+
+// TranslateError converts a MySql driver error into a *DBError matching the database error sentinels,
+// errors that can't be classified are returned unchanged.
+func (d *MySqlDialect) TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var dbErr *xqbErr.DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	driverErr := parseDriverError(err)
+	kind := errorKinds[driverErr.Number]
+	if kind == nil && driverErr.SQLState == "40001" {
+		kind = xqbErr.ErrDeadlock
+	}
+	// go-sql-driver/mysql reports a broken connection with mysql.ErrInvalidConn
+	if kind == nil && (errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysqlDriver.ErrInvalidConn)) {
+		kind = xqbErr.ErrConnectionLost
+	}
+	if kind == nil {
+		return err
+	}
+
+	message := err.Error()
+	switch kind {
+	case xqbErr.ErrUniqueViolation:
+		if match := duplicateKeyPattern.FindStringSubmatch(message); match != nil {
+			// MySql 8 prefixes the key name with the table name
+			if table, key, found := strings.Cut(match[1], "."); found {
+				driverErr.Table, driverErr.Constraint = table, key
+			} else {
+				driverErr.Constraint = match[1]
+			}
+		}
+	case xqbErr.ErrForeignKeyViolation:
+		if match := foreignKeyPattern.FindStringSubmatch(message); match != nil {
+			driverErr.Table, driverErr.Constraint, driverErr.Column = match[1], match[2], match[3]
+		}
+	case xqbErr.ErrNotNullViolation:
+		if match := columnPattern.FindStringSubmatch(message); match != nil {
+			driverErr.Column = match[1]
+		}
+	case xqbErr.ErrCheckViolation:
+		if match := checkPattern.FindStringSubmatch(message); match != nil {
+			driverErr.Constraint = match[1]
+		}
+	}
+
+	return xqbErr.NewDBError(kind, driverErr, err)
+}
+
+// parseDriverError reads the number and the SQLSTATE of *mysql.MySQLError, the errors of other drivers are
+// read by their methods (see ParseDriverError)
+func parseDriverError(err error) xqbErr.DriverError {
+	var mysqlErr *mysqlDriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		found := xqbErr.DriverError{Number: int(mysqlErr.Number)}
+		if mysqlErr.SQLState != [5]byte{} {
+			found.SQLState = string(mysqlErr.SQLState[:])
+		}
+		return found
+	}
+
+	found, _ := xqbErr.ParseDriverError(err)
+	return found
+}
@@ -0,0 +1,6 @@
+package mysql
+
+// CompileExplain compiles the statement returning the execution plan of the given query
+func (d *MySqlDialect) CompileExplain(query string) string {
+	return "EXPLAIN " + query
+}
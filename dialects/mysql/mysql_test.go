@@ -1,8 +1,13 @@
 package mysql
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
 	"github.com/iMohamedSheta/xqb/shared/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -737,3 +742,152 @@ func TestMySqlDialect_CompileDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestMySqlDialect_CompileTransactionOptions(t *testing.T) {
+	dialect := &MySqlDialect{}
+
+	statements, err := dialect.CompileTransactionOptions(&types.TxOptions{ReadOnly: true, Timeout: time.Second})
+	assert.NoError(t, err)
+	assert.Empty(t, statements)
+
+	_, err = dialect.CompileTransactionOptions(&types.TxOptions{Deferrable: true})
+	assert.ErrorIs(t, err, xqbErr.ErrUnsupportedFeature)
+}
+
+// driverError is a driver error exposing its number and SQLSTATE
+type driverError struct {
+	number  int
+	state   string
+	message string
+}
+
+func (e *driverError) Error() string {
+	if e.message == "" {
+		return "driver error"
+	}
+	return e.message
+}
+
+func (e *driverError) ErrorNumber() int {
+	return e.number
+}
+
+func (e *driverError) SQLState() string {
+	return e.state
+}
+
+type numberFields struct {
+	Number int
+}
+
+// embeddingError embeds a nil pointer holding a Number field
+type embeddingError struct {
+	*numberFields
+}
+
+func (e *embeddingError) Error() string {
+	return "Error 1213 (40001): Deadlock found when trying to get lock"
+}
+
+func TestMySqlDialect_IsRetryableError(t *testing.T) {
+	dialect := &MySqlDialect{}
+
+	assert.True(t, dialect.IsRetryableError(&driverError{number: 1213}))
+	assert.True(t, dialect.IsRetryableError(fmt.Errorf("%w: query failed %w", xqbErr.ErrQueryFailed, &driverError{number: 1213})))
+	assert.False(t, dialect.IsRetryableError(&driverError{number: 1062, state: "23000"}))
+	// the messages of errors not coming from a driver aren't parsed
+	assert.False(t, dialect.IsRetryableError(errors.New("Error 1213 (40001): Deadlock found when trying to get lock")))
+	assert.False(t, dialect.IsRetryableError(&embeddingError{}))
+	assert.False(t, dialect.IsRetryableError(errors.New("connection refused")))
+	assert.False(t, dialect.IsRetryableError(nil))
+}
+
+func TestMySqlDialect_TranslateError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		kind     error
+		expected xqbErr.DriverError
+	}{
+		{
+			name:     "duplicate entry",
+			err:      &driverError{number: 1062, state: "23000", message: "Error 1062 (23000): Duplicate entry 'john@example.com' for key 'users.users_email_unique'"},
+			kind:     xqbErr.ErrUniqueViolation,
+			expected: xqbErr.DriverError{Number: 1062, SQLState: "23000", Table: "users", Constraint: "users_email_unique"},
+		},
+		{
+			name:     "foreign key",
+			err:      &driverError{number: 1452, state: "23000", message: "Error 1452 (23000): Cannot add or update a child row: a foreign key constraint fails (`shop`.`posts`, CONSTRAINT `posts_user_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"},
+			kind:     xqbErr.ErrForeignKeyViolation,
+			expected: xqbErr.DriverError{Number: 1452, SQLState: "23000", Table: "posts", Constraint: "posts_user_id_fk", Column: "user_id"},
+		},
+		{
+			name:     "mysql driver error",
+			err:      fmt.Errorf("wrapped: %w", &mysqlDriver.MySQLError{Number: 1048, SQLState: [5]byte{'2', '3', '0', '0', '0'}, Message: "Column 'name' cannot be null"}),
+			kind:     xqbErr.ErrNotNullViolation,
+			expected: xqbErr.DriverError{Number: 1048, SQLState: "23000", Column: "name"},
+		},
+		{
+			name:     "not null",
+			err:      &driverError{number: 1048, state: "23000"},
+			kind:     xqbErr.ErrNotNullViolation,
+			expected: xqbErr.DriverError{Number: 1048, SQLState: "23000"},
+		},
+		{
+			name:     "check constraint",
+			err:      &driverError{number: 3819, state: "HY000", message: "Error 3819 (HY000): Check constraint 'users_age_check' is violated."},
+			kind:     xqbErr.ErrCheckViolation,
+			expected: xqbErr.DriverError{Number: 3819, SQLState: "HY000", Constraint: "users_age_check"},
+		},
+		{
+			name:     "deadlock",
+			err:      &driverError{number: 1213},
+			kind:     xqbErr.ErrDeadlock,
+			expected: xqbErr.DriverError{Number: 1213},
+		},
+		{
+			name:     "lock wait timeout",
+			err:      &driverError{number: 1205, state: "HY000", message: "Error 1205 (HY000): Lock wait timeout exceeded; try restarting transaction"},
+			kind:     xqbErr.ErrLockTimeout,
+			expected: xqbErr.DriverError{Number: 1205, SQLState: "HY000"},
+		},
+		{
+			name: "invalid connection",
+			err:  fmt.Errorf("wrapped: %w", mysqlDriver.ErrInvalidConn),
+			kind: xqbErr.ErrConnectionLost,
+		},
+	}
+
+	dialect := &MySqlDialect{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dialect.TranslateError(tt.err)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, tt.err)
+
+			var dbErr *xqbErr.DBError
+			assert.ErrorAs(t, err, &dbErr)
+			assert.Equal(t, tt.expected, xqbErr.DriverError{
+				Number:     dbErr.Number,
+				SQLState:   dbErr.SQLState,
+				Constraint: dbErr.Constraint,
+				Table:      dbErr.Table,
+				Column:     dbErr.Column,
+			})
+		})
+	}
+
+	unknown := &driverError{number: 1146, state: "42S02", message: "Error 1146 (42S02): Table 'shop.missing' doesn't exist"}
+	assert.Same(t, error(unknown), dialect.TranslateError(unknown))
+	// a lookalike message isn't translated
+	message := errors.New("Error 1062 (23000): Duplicate entry 'x' for key 'users.email'")
+	assert.Same(t, message, dialect.TranslateError(message))
+	invalidConn := errors.New("invalid connection")
+	assert.Same(t, invalidConn, dialect.TranslateError(invalidConn))
+	assert.Nil(t, dialect.TranslateError(nil))
+}
+
+func TestMySqlDialect_CompileExplain(t *testing.T) {
+	dialect := &MySqlDialect{}
+	assert.Equal(t, "EXPLAIN SELECT * FROM `users` WHERE `id` = ?", dialect.CompileExplain("SELECT * FROM `users` WHERE `id` = ?"))
+}
@@ -0,0 +1,30 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
+)
+
+// CompileTransactionOptions compiles the statements executed after a transaction begins.
+// MySql applies the isolation level and read only mode through the driver and has no
+// per transaction statement timeout, so the timeout is only enforced by the context.
+func (d *MySqlDialect) CompileTransactionOptions(opts *types.TxOptions) ([]string, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	if opts.Deferrable {
+		return nil, fmt.Errorf("%w: deferrable transactions are not supported by MySql dialect", xqbErr.ErrUnsupportedFeature)
+	}
+
+	return nil, nil
+}
+
+// IsRetryableError reports whether the transaction failed with a deadlock (error 1213, SQLSTATE 40001)
+// and can be retried from the start
+func (d *MySqlDialect) IsRetryableError(err error) bool {
+	return errors.Is(d.TranslateError(err), xqbErr.ErrDeadlock)
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres SQLSTATE codes translated into the database error sentinels
+var errorKinds = map[string]error{
+	"23505": xqbErr.ErrUniqueViolation,     // unique_violation
+	"23503": xqbErr.ErrForeignKeyViolation, // foreign_key_violation
+	"23502": xqbErr.ErrNotNullViolation,    // not_null_violation
+	"23514": xqbErr.ErrCheckViolation,      // check_violation
+	"40P01": xqbErr.ErrDeadlock,            // deadlock_detected
+	"55P03": xqbErr.ErrLockTimeout,         // lock_not_available
+	"40001": xqbErr.ErrSerialization,       // serialization_failure
+	"57P01": xqbErr.ErrConnectionLost,      // admin_shutdown
+	"57P02": xqbErr.ErrConnectionLost,      // crash_shutdown
+}
+
+var (
+	// duplicate key value violates unique constraint "users_email_key"
+	constraintPattern = regexp.MustCompile(`constraint "([^"]+)"`)
+	// null value in column "name" of relation "users" violates not-null constraint
+	columnPattern = regexp.MustCompile(`column "([^"]+)"(?: of relation "([^"]+)")?`)
+	// insert or update on table "posts" violates foreign key constraint "posts_user_id_fkey"
+	tablePattern = regexp.MustCompile(`(?:on table|relation) "([^"]+)"`)
+)
+
+// TranslateError converts a Postgres driver error into a *DBError matching the database error sentinels,
+// errors that can't be classified are returned unchanged.
+func (d *PostgresDialect) TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var dbErr *xqbErr.DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	driverErr := parseDriverError(err)
+	kind := errorKinds[driverErr.SQLState]
+	// class 08 - connection exception
+	if kind == nil && (strings.HasPrefix(driverErr.SQLState, "08") || errors.Is(err, driver.ErrBadConn)) {
+		kind = xqbErr.ErrConnectionLost
+	}
+	if kind == nil {
+		return err
+	}
+
+	// lib/pq and pgx expose the names as fields, the message is only read when they are missing
+	message := err.Error()
+	if driverErr.Constraint == "" {
+		if match := constraintPattern.FindStringSubmatch(message); match != nil {
+			driverErr.Constraint = match[1]
+		}
+	}
+	if driverErr.Column == "" && kind == xqbErr.ErrNotNullViolation {
+		if match := columnPattern.FindStringSubmatch(message); match != nil {
+			driverErr.Column = match[1]
+			if driverErr.Table == "" {
+				driverErr.Table = match[2]
+			}
+		}
+	}
+	if driverErr.Table == "" {
+		if match := tablePattern.FindStringSubmatch(message); match != nil {
+			driverErr.Table = match[1]
+		}
+	}
+
+	return xqbErr.NewDBError(kind, driverErr, err)
+}
+
+// parseDriverError reads the SQLSTATE and the names of *pgconn.PgError (pgx), the errors of other drivers
+// (e.g. *pq.Error of lib/pq) are read by their methods (see ParseDriverError)
+func parseDriverError(err error) xqbErr.DriverError {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return xqbErr.DriverError{
+			SQLState:   pgErr.Code,
+			Constraint: pgErr.ConstraintName,
+			Table:      pgErr.TableName,
+			Column:     pgErr.ColumnName,
+		}
+	}
+
+	found, _ := xqbErr.ParseDriverError(err)
+	return found
+}
@@ -0,0 +1,6 @@
+package postgres
+
+// CompileExplain compiles the statement returning the execution plan of the given query as a JSON document
+func (d *PostgresDialect) CompileExplain(query string) string {
+	return "EXPLAIN (FORMAT JSON) " + query
+}
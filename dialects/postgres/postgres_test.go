@@ -1,9 +1,15 @@
 package postgres
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
 	"github.com/iMohamedSheta/xqb/shared/types"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -737,3 +743,167 @@ func TestPostgresDialect_CompileDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgresDialect_CompileTransactionOptions(t *testing.T) {
+	dialect := &PostgresDialect{}
+
+	statements, err := dialect.CompileTransactionOptions(&types.TxOptions{
+		Isolation:  sql.LevelSerializable,
+		ReadOnly:   true,
+		Deferrable: true,
+		Timeout:    1500 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SET TRANSACTION DEFERRABLE", "SET LOCAL statement_timeout = 1500"}, statements)
+
+	statements, err = dialect.CompileTransactionOptions(&types.TxOptions{Timeout: time.Microsecond})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SET LOCAL statement_timeout = 1"}, statements)
+
+	_, err = dialect.CompileTransactionOptions(&types.TxOptions{Deferrable: true})
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery)
+}
+
+// codeError has a SQLSTATE looking Code field without being a driver error
+type codeError struct {
+	Code string
+}
+
+func (e *codeError) Error() string {
+	return "pq: driver error"
+}
+
+type sqlStateError struct {
+	code    string
+	message string
+}
+
+func (e *sqlStateError) Error() string {
+	if e.message == "" {
+		return "driver error"
+	}
+	return e.message
+}
+
+func (e *sqlStateError) SQLState() string {
+	return e.code
+}
+
+func TestPostgresDialect_IsRetryableError(t *testing.T) {
+	dialect := &PostgresDialect{}
+
+	assert.True(t, dialect.IsRetryableError(&sqlStateError{code: "40001"}))
+	assert.True(t, dialect.IsRetryableError(fmt.Errorf("wrapped: %w", &sqlStateError{code: "40P01"})))
+	assert.False(t, dialect.IsRetryableError(&sqlStateError{code: "23505"}))
+	// only the errors of the drivers are read
+	assert.False(t, dialect.IsRetryableError(&codeError{Code: "40001"}))
+	assert.False(t, dialect.IsRetryableError(errors.New("ERROR: could not serialize access (SQLSTATE 40001)")))
+	assert.False(t, dialect.IsRetryableError(errors.New("connection refused")))
+}
+
+// pqError mimics *pq.Error of lib/pq reading its fields by protocol code
+type pqError struct {
+	fields map[byte]string
+}
+
+func (e *pqError) Error() string {
+	return "pq: " + e.fields['M']
+}
+
+func (e *pqError) Get(field byte) string {
+	return e.fields[field]
+}
+
+func TestPostgresDialect_TranslateError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		kind     error
+		expected xqbErr.DriverError
+	}{
+		{
+			name:     "unique violation fields",
+			err:      &pqError{fields: map[byte]string{'C': "23505", 'M': "duplicate key value", 't': "users", 'n': "users_email_key"}},
+			kind:     xqbErr.ErrUniqueViolation,
+			expected: xqbErr.DriverError{SQLState: "23505", Table: "users", Constraint: "users_email_key"},
+		},
+		{
+			name:     "pgx error fields",
+			err:      fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "23503", Message: "violates foreign key constraint", TableName: "posts", ConstraintName: "posts_user_id_fkey"}),
+			kind:     xqbErr.ErrForeignKeyViolation,
+			expected: xqbErr.DriverError{SQLState: "23503", Table: "posts", Constraint: "posts_user_id_fkey"},
+		},
+		{
+			name:     "foreign key message",
+			err:      &sqlStateError{code: "23503"},
+			kind:     xqbErr.ErrForeignKeyViolation,
+			expected: xqbErr.DriverError{SQLState: "23503"},
+		},
+		{
+			name:     "not null message",
+			err:      &sqlStateError{code: "23502", message: `ERROR: null value in column "name" of relation "users" violates not-null constraint (SQLSTATE 23502)`},
+			kind:     xqbErr.ErrNotNullViolation,
+			expected: xqbErr.DriverError{SQLState: "23502", Table: "users", Column: "name"},
+		},
+		{
+			name:     "check violation",
+			err:      &sqlStateError{code: "23514", message: `ERROR: new row for relation "users" violates check constraint "users_age_check" (SQLSTATE 23514)`},
+			kind:     xqbErr.ErrCheckViolation,
+			expected: xqbErr.DriverError{SQLState: "23514", Table: "users", Constraint: "users_age_check"},
+		},
+		{
+			name:     "deadlock",
+			err:      &sqlStateError{code: "40P01"},
+			kind:     xqbErr.ErrDeadlock,
+			expected: xqbErr.DriverError{SQLState: "40P01"},
+		},
+		{
+			name:     "lock not available",
+			err:      &sqlStateError{code: "55P03"},
+			kind:     xqbErr.ErrLockTimeout,
+			expected: xqbErr.DriverError{SQLState: "55P03"},
+		},
+		{
+			name:     "serialization failure",
+			err:      &sqlStateError{code: "40001"},
+			kind:     xqbErr.ErrSerialization,
+			expected: xqbErr.DriverError{SQLState: "40001"},
+		},
+		{
+			name:     "connection failure",
+			err:      &sqlStateError{code: "08006"},
+			kind:     xqbErr.ErrConnectionLost,
+			expected: xqbErr.DriverError{SQLState: "08006"},
+		},
+	}
+
+	dialect := &PostgresDialect{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dialect.TranslateError(tt.err)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, tt.err)
+
+			var dbErr *xqbErr.DBError
+			assert.ErrorAs(t, err, &dbErr)
+			assert.Equal(t, tt.expected, xqbErr.DriverError{
+				Number:     dbErr.Number,
+				SQLState:   dbErr.SQLState,
+				Constraint: dbErr.Constraint,
+				Table:      dbErr.Table,
+				Column:     dbErr.Column,
+			})
+		})
+	}
+
+	unknown := &sqlStateError{code: "42P01"}
+	assert.Same(t, error(unknown), dialect.TranslateError(unknown))
+
+	lookalike := &codeError{Code: "23505"}
+	assert.Same(t, error(lookalike), dialect.TranslateError(lookalike))
+}
+
+func TestPostgresDialect_CompileExplain(t *testing.T) {
+	dialect := &PostgresDialect{}
+	assert.Equal(t, `EXPLAIN (FORMAT JSON) SELECT * FROM "users" WHERE "id" = $1`, dialect.CompileExplain(`SELECT * FROM "users" WHERE "id" = $1`))
+}
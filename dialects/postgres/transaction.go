@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
+)
+
+// CompileTransactionOptions compiles the statements executed after a transaction begins.
+// The isolation level and read only mode are applied by the driver, DEFERRABLE and the
+// server side statement timeout (scoped to the transaction with SET LOCAL) are not mapped by database/sql.
+func (d *PostgresDialect) CompileTransactionOptions(opts *types.TxOptions) ([]string, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	var statements []string
+
+	if opts.Deferrable {
+		if opts.Isolation != sql.LevelSerializable || !opts.ReadOnly {
+			return nil, fmt.Errorf("%w: deferrable transactions must be serializable and read only", xqbErr.ErrInvalidQuery)
+		}
+		statements = append(statements, "SET TRANSACTION DEFERRABLE")
+	}
+
+	if opts.Timeout > 0 {
+		// statement_timeout = 0 disables the timeout so sub millisecond timeouts are rounded up
+		milliseconds := max(opts.Timeout.Milliseconds(), 1)
+		statements = append(statements, fmt.Sprintf("SET LOCAL statement_timeout = %d", milliseconds))
+	}
+
+	return statements, nil
+}
+
+// IsRetryableError reports whether the transaction failed with a serialization failure (SQLSTATE 40001)
+// or a deadlock (SQLSTATE 40P01) and can be retried from the start
+func (d *PostgresDialect) IsRetryableError(err error) bool {
+	translated := d.TranslateError(err)
+	return errors.Is(translated, xqbErr.ErrSerialization) || errors.Is(translated, xqbErr.ErrDeadlock)
+}
@@ -36,4 +36,41 @@ var (
 
 	// ErrClosingConnection is returned when a database connection could not be closed.
 	ErrClosingConnection = errors.ErrClosingConnection
+
+	// ErrInvalidConfig is returned when a connection config is incomplete, duplicated or can't be parsed.
+	ErrInvalidConfig = errors.ErrInvalidConfig
 )
+
+// Database Errors, errors.Is(err, xqb.ErrUniqueViolation) matches the driver errors translated by the dialect
+var (
+	// ErrUniqueViolation is returned when an insert or update violates a unique constraint or primary key.
+	ErrUniqueViolation = errors.ErrUniqueViolation
+
+	// ErrForeignKeyViolation is returned when a statement violates a foreign key constraint.
+	ErrForeignKeyViolation = errors.ErrForeignKeyViolation
+
+	// ErrNotNullViolation is returned when a NULL value is written to a NOT NULL column.
+	ErrNotNullViolation = errors.ErrNotNullViolation
+
+	// ErrCheckViolation is returned when a statement violates a CHECK constraint.
+	ErrCheckViolation = errors.ErrCheckViolation
+
+	// ErrDeadlock is returned when the transaction was chosen as a deadlock victim and rolled back.
+	ErrDeadlock = errors.ErrDeadlock
+
+	// ErrLockTimeout is returned when a lock couldn't be acquired in time or immediately (NOWAIT).
+	ErrLockTimeout = errors.ErrLockTimeout
+
+	// ErrSerialization is returned when a serializable transaction couldn't be serialized with concurrent ones.
+	ErrSerialization = errors.ErrSerialization
+
+	// ErrConnectionLost is returned when the connection to the database server was lost.
+	ErrConnectionLost = errors.ErrConnectionLost
+)
+
+// DBError is a database error translated by the dialect, use errors.As to read its
+// constraint, table and column names.
+type DBError = errors.DBError
+
+// NumberedError is implemented by driver errors exposing a vendor error number (e.g. MySql 1213).
+type NumberedError = errors.NumberedError
@@ -0,0 +1,49 @@
+package xqb_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DatabaseErrors_TranslatedOnExecution(t *testing.T) {
+	duplicate := &mysqlError{Number: 1062, State: [5]byte{'2', '3', '0', '0', '0'}, Message: "Duplicate entry 'john@example.com' for key 'users.users_email_unique'"}
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return nil, duplicate
+	})
+
+	err := xqb.Table("users").Connection(fdb.name).Insert([]map[string]any{{"email": "john@example.com"}})
+
+	assert.ErrorIs(t, err, xqb.ErrUniqueViolation)
+	assert.ErrorIs(t, err, duplicate)
+
+	var dbErr *xqb.DBError
+	assert.True(t, errors.As(err, &dbErr))
+	assert.Equal(t, "users", dbErr.Table)
+	assert.Equal(t, "users_email_unique", dbErr.Constraint)
+	assert.Equal(t, 1062, dbErr.Number)
+}
+
+func Test_DatabaseErrors_KeepQueryFailedWrapping(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, func(query string, args []any) (*fakeResult, error) {
+		return nil, &pgError{Code: "55P03", Message: "could not obtain lock on row in relation \"users\""}
+	})
+
+	_, err := xqb.Table("users").Connection(fdb.name).LockForUpdate().NoWaitLocked().Get()
+
+	assert.ErrorIs(t, err, xqb.ErrQueryFailed)
+	assert.ErrorIs(t, err, xqb.ErrLockTimeout)
+}
+
+func Test_DatabaseErrors_UnknownErrorsUnchanged(t *testing.T) {
+	failure := errors.New("syntax error")
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return nil, failure
+	})
+
+	_, err := xqb.Sql("SELEC 1").Connection(fdb.name).Execute()
+
+	assert.Same(t, failure, err)
+}
@@ -0,0 +1,178 @@
+package xqb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+)
+
+// keyRange is an inclusive range of primary key values processed by one chunk
+type keyRange struct {
+	from int64
+	to   int64
+}
+
+// ChunksParallel processes results in chunks concurrently using a pool of workers,
+// the keyspace of the "id" column is split into ranges of chunkSize (see ChunksParallelById).
+func (qb *QueryBuilder) ChunksParallel(chunkSize int, workers int, closure func(results []map[string]any) error) error {
+	return qb.ChunksParallelById(chunkSize, workers, "id", closure)
+}
+
+// ChunksParallelById processes results in chunks concurrently using a pool of workers.
+// It probes MIN/MAX of the integer key column then each worker runs the range queries
+// (WHERE column BETWEEN from AND to) with its own cloned builder on the connection pool.
+// The first error cancels the remaining work and all the errors are returned joined, the errors of the
+// range queries are wrapped in ErrQueryFailed while the errors returned by the closure are returned unchanged.
+// The closure is called from multiple goroutines so it must be safe for concurrent use, and so are the hooks,
+// interceptors and listeners of the settings (e.g. DefaultSettings().OnBeforeQuery) fired by every range query.
+// The ranges split the keyspace, not the rows: sparse keys produce many empty range queries,
+// prefer ChunkById when the gaps between the keys are large compared to chunkSize.
+func (qb *QueryBuilder) ChunksParallelById(chunkSize int, workers int, column string, closure func(results []map[string]any) error) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("%w: ChunksParallel() chunk size must be greater than 0", xqbErr.ErrInvalidQuery)
+	}
+
+	if workers <= 0 {
+		return fmt.Errorf("%w: ChunksParallel() workers must be greater than 0", xqbErr.ErrInvalidQuery)
+	}
+
+	if qb.activeTx() != nil {
+		return fmt.Errorf("%w: ChunksParallel() can't share a transaction between workers", xqbErr.ErrUnsupportedFeature)
+	}
+
+	if column == "" {
+		column = "id"
+	}
+
+	parent := qb.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	minId, maxId, found, err := qb.Clone().WithContext(ctx).probeKeyRange(column)
+	if err != nil {
+		if parent.Err() != nil {
+			return fmt.Errorf("%w: ChunksParallel() failed to process chunks: %w", xqbErr.ErrQueryFailed, parent.Err())
+		}
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+		cancel()
+	}
+
+	ranges := make(chan keyRange)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range ranges {
+				if ctx.Err() != nil {
+					return
+				}
+
+				chunk := qb.Clone().WithContext(ctx)
+				chunk.groupOrWheres()
+				chunk.WhereBetween(column, r.from, r.to).OrderBy(column, "ASC")
+				chunk.limit = 0
+				chunk.offset = 0
+
+				results, err := chunk.Get()
+				if err != nil {
+					if ctx.Err() == nil {
+						fail(fmt.Errorf("%w: ChunksParallel() failed to query the keys %d to %d: %w", xqbErr.ErrQueryFailed, r.from, r.to, err))
+					}
+					return
+				}
+
+				if len(results) == 0 {
+					continue
+				}
+
+				if err := closure(results); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+produce:
+	for from := minId; from <= maxId; from += int64(chunkSize) {
+		to := from + int64(chunkSize) - 1
+		if to > maxId || to < from {
+			to = maxId
+		}
+
+		select {
+		case ranges <- keyRange{from: from, to: to}:
+		case <-ctx.Done():
+			break produce
+		}
+
+		if to == maxId {
+			break
+		}
+	}
+	close(ranges)
+	wg.Wait()
+
+	// Report the cancellation of the caller context when no worker failed
+	if len(errs) == 0 && parent.Err() != nil {
+		return fmt.Errorf("%w: ChunksParallel() failed to process chunks: %w", xqbErr.ErrQueryFailed, parent.Err())
+	}
+
+	if len(errs) == 1 {
+		return errs[0]
+	}
+
+	return errors.Join(errs...)
+}
+
+// probeKeyRange returns the MIN and MAX values of the key column for the current conditions
+func (qb *QueryBuilder) probeKeyRange(column string) (int64, int64, bool, error) {
+	qb.resetForPaginationCount()
+	qb.columns = []any{
+		fmt.Sprintf("MIN(%s) as min_key", qb.Wrap(column)),
+		fmt.Sprintf("MAX(%s) as max_key", qb.Wrap(column)),
+	}
+
+	data, err := qb.Get()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	if len(data) != 1 {
+		return 0, 0, false, fmt.Errorf("%w: ChunksParallel() expected one row as result of the key range probe, got %d rows", xqbErr.ErrInvalidResult, len(data))
+	}
+
+	if data[0]["min_key"] == nil || data[0]["max_key"] == nil {
+		return 0, 0, false, nil
+	}
+
+	converter := &numericConverter{}
+	minId, okMin := converter.toInt64(data[0]["min_key"])
+	maxId, okMax := converter.toInt64(data[0]["max_key"])
+	if !okMin || !okMax {
+		return 0, 0, false, fmt.Errorf("%w: ChunksParallel() key column %q must be an integer", xqbErr.ErrInvalidResult, column)
+	}
+
+	return minId, maxId, true, nil
+}
@@ -0,0 +1,164 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// rangeHandler answers the MIN/MAX probe and the BETWEEN range queries for the given ids
+func rangeHandler(ids ...int64) fakeHandler {
+	return func(query string, args []any) (*fakeResult, error) {
+		if strings.Contains(query, "MIN(") {
+			if len(ids) == 0 {
+				return &fakeResult{columns: []string{"min_key", "max_key"}, rows: [][]driver.Value{{nil, nil}}}, nil
+			}
+			return &fakeResult{columns: []string{"min_key", "max_key"}, rows: [][]driver.Value{{ids[0], ids[len(ids)-1]}}}, nil
+		}
+
+		from, to := args[len(args)-2].(int64), args[len(args)-1].(int64)
+		res := &fakeResult{columns: []string{"id"}}
+		for _, id := range ids {
+			if id >= from && id <= to {
+				res.rows = append(res.rows, []driver.Value{id})
+			}
+		}
+		return res, nil
+	}
+}
+
+// clearQueryHooks removes the global query hooks during the test, the hooks of other tests aren't safe to call
+// from the worker goroutines, the previous hooks are restored when the test ends
+func clearQueryHooks(t *testing.T) {
+	t.Helper()
+	settings := xqb.DefaultSettings()
+	before, after := settings.GetOnBeforeQuery(), settings.GetOnAfterQuery()
+	settings.OnBeforeQuery(nil)
+	settings.OnAfterQuery(nil)
+	t.Cleanup(func() {
+		settings.OnBeforeQuery(before)
+		settings.OnAfterQuery(after)
+	})
+}
+
+func Test_ChunksParallel(t *testing.T) {
+	clearQueryHooks(t)
+	fdb := newFakeConnection(t, xqb.DialectMySql, rangeHandler(1, 2, 3, 4, 5, 9, 10))
+
+	var (
+		mu  sync.Mutex
+		ids []int
+	)
+	err := xqb.Table("users").Connection(fdb.name).Where("active", "=", true).ChunksParallel(3, 2, func(results []map[string]any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, row := range results {
+			ids = append(ids, int(row["id"].(int64)))
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	sort.Ints(ids)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 9, 10}, ids)
+
+	statements := fdb.Statements()
+	assert.Equal(t, "SELECT MIN(`id`) AS `min_key`, MAX(`id`) AS `max_key` FROM `users` WHERE `active` = ?", statements[0])
+	assert.Len(t, statements, 5)
+	assert.Contains(t, statements, "SELECT * FROM `users` WHERE `active` = ? AND `id` BETWEEN ? AND ? ORDER BY `id` ASC")
+}
+
+func Test_ChunksParallel_EmptyTable(t *testing.T) {
+	clearQueryHooks(t)
+	fdb := newFakeConnection(t, xqb.DialectMySql, rangeHandler())
+
+	called := false
+	err := xqb.Table("users").Connection(fdb.name).ChunksParallel(10, 4, func(results []map[string]any) error {
+		called = true
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, called)
+	assert.Len(t, fdb.Statements(), 1)
+}
+
+func Test_ChunksParallel_StopsOnError(t *testing.T) {
+	clearQueryHooks(t)
+	fdb := newFakeConnection(t, xqb.DialectMySql, rangeHandler(1, 2, 3, 4, 5, 6, 7, 8, 9, 10))
+	failure := errors.New("failed chunk")
+
+	err := xqb.Table("users").Connection(fdb.name).ChunksParallel(1, 1, func(results []map[string]any) error {
+		return failure
+	})
+
+	// the closure error is returned unchanged
+	assert.Equal(t, failure, err)
+	assert.Len(t, fdb.Statements(), 2)
+}
+
+func Test_ChunksParallel_QueryErrorIsQueryFailed(t *testing.T) {
+	clearQueryHooks(t)
+	failure := errors.New("connection lost")
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		if strings.Contains(query, "BETWEEN") {
+			return nil, failure
+		}
+		return rangeHandler(1, 2)(query, args)
+	})
+
+	err := xqb.Table("users").Connection(fdb.name).ChunksParallel(1, 1, func(results []map[string]any) error {
+		return nil
+	})
+
+	assert.ErrorIs(t, err, xqbErr.ErrQueryFailed)
+	assert.ErrorIs(t, err, failure)
+}
+
+func Test_ChunksParallel_ContextCanceled(t *testing.T) {
+	clearQueryHooks(t)
+	fdb := newFakeConnection(t, xqb.DialectMySql, rangeHandler(1, 2, 3))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := xqb.Table("users").Connection(fdb.name).WithContext(ctx).ChunksParallel(1, 2, func(results []map[string]any) error {
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_ChunksParallel_RejectsTransaction(t *testing.T) {
+	clearQueryHooks(t)
+	err := xqb.Table("users").WithTx(&sql.Tx{}).ChunksParallel(10, 2, func(results []map[string]any) error {
+		return nil
+	})
+
+	assert.ErrorIs(t, err, xqbErr.ErrUnsupportedFeature)
+}
+
+func Test_ModelChunksParallel(t *testing.T) {
+	clearQueryHooks(t)
+	fdb := newFakeConnection(t, xqb.DialectMySql, rangeHandler(1, 2, 3))
+
+	var count int32
+	var mu sync.Mutex
+	err := xqb.Model[User]().Connection(fdb.name).ChunksParallel(2, 2, func(users []User) error {
+		mu.Lock()
+		defer mu.Unlock()
+		count += int32(len(users))
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), count)
+}
@@ -0,0 +1,117 @@
+package xqb
+
+import (
+	"database/sql"
+	"fmt"
+	"iter"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+)
+
+// Cursor executes the query and streams the results one row at a time.
+// A single *sql.Rows is kept open while iterating and it is closed when the
+// loop ends or the caller breaks out of it.
+//
+// Example:
+//
+//	for row, err := range xqb.Table("users").Cursor() {
+//		if err != nil {
+//			return err
+//		}
+//		// process row...
+//	}
+func (qb *QueryBuilder) Cursor() iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		query, args, err := qb.GetSql()
+		if err != nil {
+			yield(nil, fmt.Errorf("%w: Cursor() Failed to build the sql query, %v", xqbErr.ErrInvalidQuery, err))
+			return
+		}
+
+		sqlQuery := qb.sqlQuery(query, args).withRowsCount()
+		rows, err := sqlQuery.Query()
+
+		if err != nil {
+			yield(nil, fmt.Errorf("%w: Cursor() Invalid query sql query error %w", xqbErr.ErrQueryFailed, err))
+			return
+		}
+
+		// the execution event is emitted once the loop ends with the rows read so far
+		var count int64
+		defer func() {
+			_ = rows.Close()
+			sqlQuery.finish(count, err)
+		}()
+
+		scanner, err := newRowScanner(rows)
+		if err != nil {
+			yield(nil, fmt.Errorf("%w: Cursor() failed to retrieve columns %v", xqbErr.ErrInvalidResult, err))
+			return
+		}
+
+		for rows.Next() {
+			result, scanErr := scanner.scan(rows)
+			if scanErr != nil {
+				err = fmt.Errorf("%w: Cursor() failed to scan result row %v", xqbErr.ErrInvalidResult, scanErr)
+				yield(nil, err)
+				return
+			}
+			count++
+
+			if !yield(result, nil) {
+				return
+			}
+		}
+
+		if rowsErr := rows.Err(); rowsErr != nil {
+			err = fmt.Errorf("%w: Cursor() failed to scan result rows %v", xqbErr.ErrInvalidResult, rowsErr)
+			yield(nil, err)
+		}
+	}
+}
+
+// rowScanner scans the current row of *sql.Rows into a map reusing the same scan buffers
+type rowScanner struct {
+	columns   []string
+	values    []any
+	valuePtrs []any
+}
+
+// newRowScanner prepares the scan buffers for the columns of the given rows
+func newRowScanner(rows *sql.Rows) (*rowScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	return &rowScanner{
+		columns:   columns,
+		values:    values,
+		valuePtrs: valuePtrs,
+	}, nil
+}
+
+// scan reads the current row into a new map, []byte values are converted to string
+func (s *rowScanner) scan(rows *sql.Rows) (map[string]any, error) {
+	if err := rows.Scan(s.valuePtrs...); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(s.columns))
+	for i, col := range s.columns {
+		switch v := s.values[i].(type) {
+		case []byte:
+			result[col] = string(v)
+		default:
+			result[col] = v
+		}
+	}
+
+	return result, nil
+}
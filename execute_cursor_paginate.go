@@ -0,0 +1,271 @@
+package xqb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
+)
+
+// CursorPaginationMeta holds the metadata of a cursor paginated result.
+// NextCursor and PrevCursor are opaque tokens, they are empty when there is no page in that direction.
+type CursorPaginationMeta struct {
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor"`
+	PrevCursor string `json:"prev_cursor"`
+}
+
+// paginationCursor is the decoded form of a cursor token
+// it holds the boundary values keyed by the full ORDER BY column and the direction to paginate
+type paginationCursor struct {
+	Values map[string]any `json:"values"`
+	Prev   bool           `json:"prev,omitempty"`
+}
+
+// CursorPaginate returns results paginated with keyset conditions derived from the OrderBy columns.
+// Pass an empty cursor for the first page then the NextCursor/PrevCursor tokens from the returned meta.
+// The ORDER BY columns must be selected and the last one should be unique (e.g. id) to keep pages stable.
+// The values are read from the result key of the column (id for posts.id), select the column with its full
+// name as alias when two ORDER BY columns share a result key. NULL values can't be used as a boundary and fail.
+//
+// Example:
+//
+//	rows, meta, err := xqb.Table("posts").OrderByDesc("created_at").OrderByDesc("id").CursorPaginate(20, token)
+func (qb *QueryBuilder) CursorPaginate(perPage int, cursor string) ([]map[string]any, *CursorPaginationMeta, error) {
+	decoded, err := qb.applyCursorPagination(perPage, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, err := qb.Get()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasMore := len(results) > perPage
+	if hasMore {
+		results = results[:perPage]
+	}
+
+	isPrev := decoded != nil && decoded.Prev
+	if isPrev {
+		slices.Reverse(results)
+	}
+
+	meta := &CursorPaginationMeta{PerPage: perPage}
+	if len(results) == 0 {
+		return results, meta, nil
+	}
+
+	// There is a next page when more rows were found going forward or when we came back from it
+	if hasMore || isPrev {
+		if meta.NextCursor, err = qb.encodeCursor(results[len(results)-1], false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// There is a previous page when more rows were found going backward or when we came forward from it
+	if (isPrev && hasMore) || (decoded != nil && !isPrev) {
+		if meta.PrevCursor, err = qb.encodeCursor(results[0], true); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return results, meta, nil
+}
+
+// CursorPaginateSql returns the sql query for CursorPaginate()
+func (qb *QueryBuilder) CursorPaginateSql(perPage int, cursor string) (string, []any, error) {
+	if _, err := qb.applyCursorPagination(perPage, cursor); err != nil {
+		return "", nil, err
+	}
+
+	return qb.ToSql()
+}
+
+// applyCursorPagination decodes the cursor and applies the keyset conditions, ordering and limit to the query
+func (qb *QueryBuilder) applyCursorPagination(perPage int, cursor string) (*paginationCursor, error) {
+	if perPage <= 0 {
+		return nil, fmt.Errorf("%w: CursorPaginate() per page must be greater than 0", xqbErr.ErrInvalidQuery)
+	}
+
+	orders, err := qb.cursorOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded *paginationCursor
+	if cursor != "" {
+		decoded, err = decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := qb.whereCursor(orders, decoded); err != nil {
+			return nil, err
+		}
+
+		// Walk backward by reversing the ordering, the results are reversed back after fetching
+		if decoded.Prev {
+			reversed := make([]*types.OrderBy, len(orders))
+			for i, order := range orders {
+				direction := "DESC"
+				if isDescending(order.Direction) {
+					direction = "ASC"
+				}
+				reversed[i] = &types.OrderBy{Column: order.Column, Direction: direction}
+			}
+			qb.orderBy = reversed
+		}
+	}
+
+	qb.limit = perPage + 1
+	qb.offset = 0
+
+	return decoded, nil
+}
+
+// cursorOrders returns the ORDER BY columns used to build the keyset conditions
+func (qb *QueryBuilder) cursorOrders() ([]*types.OrderBy, error) {
+	if len(qb.orderBy) == 0 {
+		return nil, fmt.Errorf("%w: CursorPaginate() requires at least one OrderBy column", xqbErr.ErrInvalidQuery)
+	}
+
+	for _, order := range qb.orderBy {
+		if order.Raw != nil || order.Column == "" {
+			return nil, fmt.Errorf("%w: CursorPaginate() doesn't support raw OrderBy expressions", xqbErr.ErrInvalidQuery)
+		}
+	}
+
+	return qb.orderBy, nil
+}
+
+// whereCursor adds the keyset conditions for the cursor boundary values
+// Example for ORDER BY a ASC, b DESC: (a > ? OR (a = ? AND b < ?))
+func (qb *QueryBuilder) whereCursor(orders []*types.OrderBy, cursor *paginationCursor) error {
+	var branches []*types.WhereCondition
+
+	for i, order := range orders {
+		value, ok := cursor.Values[order.Column]
+		if !ok || value == nil {
+			return fmt.Errorf("%w: CursorPaginate() cursor is missing the value of column %q", xqbErr.ErrInvalidQuery, order.Column)
+		}
+
+		operator := ">"
+		if isDescending(order.Direction) != cursor.Prev {
+			operator = "<"
+		}
+
+		var conditions []*types.WhereCondition
+		for _, previous := range orders[:i] {
+			conditions = append(conditions, &types.WhereCondition{
+				Column:    previous.Column,
+				Operator:  "=",
+				Value:     cursor.Values[previous.Column],
+				Connector: types.AND,
+			})
+		}
+		conditions = append(conditions, &types.WhereCondition{
+			Column:    order.Column,
+			Operator:  operator,
+			Value:     value,
+			Connector: types.AND,
+		})
+
+		branch := conditions[0]
+		if len(conditions) > 1 {
+			branch = &types.WhereCondition{Group: conditions}
+		}
+		branch.Connector = types.OR
+		branches = append(branches, branch)
+	}
+
+	condition := branches[0]
+	if len(branches) > 1 {
+		condition = &types.WhereCondition{Group: branches}
+	}
+	condition.Connector = types.AND
+
+	qb.groupOrWheres()
+	qb.where = append(qb.where, condition)
+
+	return nil
+}
+
+// encodeCursor creates the cursor token from the ORDER BY column values of the given row
+func (qb *QueryBuilder) encodeCursor(row map[string]any, prev bool) (string, error) {
+	values := make(map[string]any, len(qb.orderBy))
+	keys := make(map[string]string, len(qb.orderBy))
+	for _, order := range qb.orderBy {
+		value, ok := row[order.Column]
+		if !ok {
+			key := resultKey(order.Column)
+			if other, found := keys[key]; found {
+				return "", fmt.Errorf("%w: CursorPaginate() columns %q and %q share the result key %q, select them with their full name as alias", xqbErr.ErrInvalidQuery, other, order.Column, key)
+			}
+			keys[key] = order.Column
+
+			if value, ok = row[key]; !ok {
+				return "", fmt.Errorf("%w: CursorPaginate() column %q not found in results, it must be selected", xqbErr.ErrInvalidResult, key)
+			}
+		}
+		if value == nil {
+			return "", fmt.Errorf("%w: CursorPaginate() column %q is NULL, it can't be used as a cursor boundary", xqbErr.ErrInvalidResult, order.Column)
+		}
+		values[order.Column] = value
+	}
+
+	payload, err := json.Marshal(paginationCursor{Values: values, Prev: prev})
+	if err != nil {
+		return "", fmt.Errorf("%w: CursorPaginate() failed to encode cursor %v", xqbErr.ErrInvalidResult, err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// decodeCursor decodes a cursor token, integer values are decoded as int64 to keep large ids exact
+func decodeCursor(token string) (*paginationCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: CursorPaginate() invalid cursor %v", xqbErr.ErrInvalidQuery, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var cursor paginationCursor
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, fmt.Errorf("%w: CursorPaginate() invalid cursor %v", xqbErr.ErrInvalidQuery, err)
+	}
+
+	for key, value := range cursor.Values {
+		number, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+		if i, err := number.Int64(); err == nil {
+			cursor.Values[key] = i
+		} else if f, err := number.Float64(); err == nil {
+			cursor.Values[key] = f
+		}
+	}
+
+	return &cursor, nil
+}
+
+// resultKey returns the result key of an ORDER BY column (the column without the table prefix)
+func resultKey(column string) string {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		return column[i+1:]
+	}
+	return column
+}
+
+func isDescending(direction string) bool {
+	return strings.EqualFold(strings.TrimSpace(direction), "DESC")
+}
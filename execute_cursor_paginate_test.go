@@ -0,0 +1,182 @@
+package xqb_test
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeTestCursor(t *testing.T, values map[string]any, prev bool) string {
+	t.Helper()
+	payload, err := json.Marshal(map[string]any{"values": values, "prev": prev})
+	assert.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func Test_CursorPaginateSql_FirstPage(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, dialect types.Dialect) {
+		sql, bindings, err := xqb.Table("posts").SetDialect(dialect).
+			Where("published", "=", true).
+			OrderByDesc("id").
+			CursorPaginateSql(10, "")
+
+		expected := map[types.Dialect]string{
+			types.DialectMySql:    "SELECT * FROM `posts` WHERE `published` = ? ORDER BY `id` DESC LIMIT 11",
+			types.DialectPostgres: `SELECT * FROM "posts" WHERE "published" = $1 ORDER BY "id" DESC LIMIT 11`,
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected[dialect], sql)
+		assert.Equal(t, []any{true}, bindings)
+	})
+}
+
+func Test_CursorPaginateSql_MixedDirections(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, dialect types.Dialect) {
+		cursor := encodeTestCursor(t, map[string]any{"score": 90, "posts.id": 7}, false)
+
+		sql, bindings, err := xqb.Table("posts").SetDialect(dialect).
+			Where("type", "=", "news").
+			OrWhere("type", "=", "blog").
+			OrderByDesc("score").
+			OrderByAsc("posts.id").
+			CursorPaginateSql(10, cursor)
+
+		expected := map[types.Dialect]string{
+			types.DialectMySql:    "SELECT * FROM `posts` WHERE (`type` = ? OR `type` = ?) AND (`score` < ? OR (`score` = ? AND `posts`.`id` > ?)) ORDER BY `score` DESC, `posts`.`id` ASC LIMIT 11",
+			types.DialectPostgres: `SELECT * FROM "posts" WHERE ("type" = $1 OR "type" = $2) AND ("score" < $3 OR ("score" = $4 AND "posts"."id" > $5)) ORDER BY "score" DESC, "posts"."id" ASC LIMIT 11`,
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected[dialect], sql)
+		assert.Equal(t, []any{"news", "blog", int64(90), int64(90), int64(7)}, bindings)
+	})
+}
+
+func Test_CursorPaginateSql_PrevCursorReversesOrder(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, dialect types.Dialect) {
+		cursor := encodeTestCursor(t, map[string]any{"id": 20}, true)
+
+		sql, bindings, err := xqb.Table("posts").SetDialect(dialect).
+			OrderByDesc("id").
+			CursorPaginateSql(5, cursor)
+
+		expected := map[types.Dialect]string{
+			types.DialectMySql:    "SELECT * FROM `posts` WHERE `id` > ? ORDER BY `id` ASC LIMIT 6",
+			types.DialectPostgres: `SELECT * FROM "posts" WHERE "id" > $1 ORDER BY "id" ASC LIMIT 6`,
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected[dialect], sql)
+		assert.Equal(t, []any{int64(20)}, bindings)
+	})
+}
+
+func Test_CursorPaginateSql_Errors(t *testing.T) {
+	_, _, err := xqb.Table("posts").CursorPaginateSql(10, "")
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery)
+
+	_, _, err = xqb.Table("posts").OrderByDesc("id").CursorPaginateSql(10, "not-a-cursor")
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery)
+
+	_, _, err = xqb.Table("posts").OrderByDesc("id").CursorPaginateSql(10, encodeTestCursor(t, map[string]any{"name": "x"}, false))
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery)
+
+	_, _, err = xqb.Table("posts").OrderByDesc("id").CursorPaginateSql(10, encodeTestCursor(t, map[string]any{"id": nil}, false))
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery)
+}
+
+// idsHandler serves rows with the ids in the given order for every query
+func idsHandler(ids ...int64) fakeHandler {
+	return func(query string, args []any) (*fakeResult, error) {
+		res := &fakeResult{columns: []string{"id"}}
+		for _, id := range ids {
+			res.rows = append(res.rows, []driver.Value{id})
+		}
+		return res, nil
+	}
+}
+
+func Test_CursorPaginate_NextAndPrev(t *testing.T) {
+	// first page: 3 rows fetched for perPage 2 means there is a next page
+	fdb := newFakeConnection(t, xqb.DialectMySql, idsHandler(1, 2, 3))
+	rows, meta, err := xqb.Table("posts").Connection(fdb.name).OrderByAsc("id").CursorPaginate(2, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, 2, meta.PerPage)
+	assert.NotEmpty(t, meta.NextCursor)
+	assert.Empty(t, meta.PrevCursor)
+
+	// following the next cursor filters after the last row and exposes a prev cursor
+	fdb.handler = idsHandler(3)
+	rows, meta, err = xqb.Table("posts").Connection(fdb.name).OrderByAsc("id").CursorPaginate(2, meta.NextCursor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": int64(3)}}, rows)
+	assert.Empty(t, meta.NextCursor)
+	assert.NotEmpty(t, meta.PrevCursor)
+	assert.Equal(t, "SELECT * FROM `posts` WHERE `id` > ? ORDER BY `id` ASC LIMIT 3", fdb.Statements()[1])
+
+	// going back fetches in reverse order and restores the page order
+	fdb.handler = idsHandler(2, 1)
+	rows, meta, err = xqb.Table("posts").Connection(fdb.name).OrderByAsc("id").CursorPaginate(2, meta.PrevCursor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": int64(1)}, {"id": int64(2)}}, rows)
+	assert.NotEmpty(t, meta.NextCursor)
+	assert.Empty(t, meta.PrevCursor)
+	assert.Equal(t, "SELECT * FROM `posts` WHERE `id` < ? ORDER BY `id` DESC LIMIT 3", fdb.Statements()[2])
+}
+
+func Test_ModelCursorPaginate(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, idsHandler(9, 8))
+
+	users, meta, err := xqb.Model[User]().Connection(fdb.name).OrderByDesc("id").CursorPaginate(5, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, 9, users[0].ID)
+	assert.Empty(t, meta.NextCursor)
+	assert.Empty(t, meta.PrevCursor)
+}
+
+func Test_CursorPaginate_KeysByFullColumn(t *testing.T) {
+	handler := func(query string, args []any) (*fakeResult, error) {
+		return &fakeResult{
+			columns: []string{"users.id", "id"},
+			rows:    [][]driver.Value{{int64(1), int64(10)}, {int64(1), int64(11)}},
+		}, nil
+	}
+	fdb := newFakeConnection(t, xqb.DialectMySql, handler)
+
+	_, meta, err := xqb.Table("posts").Connection(fdb.name).
+		Select("users.id AS `users.id`", "posts.id").
+		OrderByAsc("users.id").OrderByAsc("posts.id").
+		CursorPaginate(1, "")
+	assert.NoError(t, err)
+
+	sql, bindings, err := xqb.Table("posts").OrderByAsc("users.id").OrderByAsc("posts.id").CursorPaginateSql(1, meta.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM `posts` WHERE (`users`.`id` > ? OR (`users`.`id` = ? AND `posts`.`id` > ?)) ORDER BY `users`.`id` ASC, `posts`.`id` ASC LIMIT 2", sql)
+	assert.Equal(t, []any{int64(1), int64(1), int64(10)}, bindings)
+}
+
+func Test_CursorPaginate_RejectsCollidingAndNullValues(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, idsHandler(1, 2))
+	_, _, err := xqb.Table("posts").Connection(fdb.name).OrderByAsc("users.id").OrderByAsc("posts.id").CursorPaginate(1, "")
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidQuery)
+
+	nulls := func(query string, args []any) (*fakeResult, error) {
+		return &fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{nil}, {nil}}}, nil
+	}
+	fdb = newFakeConnection(t, xqb.DialectMySql, nulls)
+	_, _, err = xqb.Table("posts").Connection(fdb.name).OrderByAsc("id").CursorPaginate(1, "")
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidResult)
+}
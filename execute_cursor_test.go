@@ -0,0 +1,85 @@
+package xqb_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func usersRows(count int) *fakeResult {
+	res := &fakeResult{columns: []string{"id", "name"}}
+	for i := 1; i <= count; i++ {
+		res.rows = append(res.rows, []driver.Value{int64(i), []byte("user")})
+	}
+	return res
+}
+
+func Test_Cursor_StreamsRows(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(3), nil
+	})
+
+	var ids []any
+	for row, err := range xqb.Table("users").Connection(fdb.name).Cursor() {
+		assert.NoError(t, err)
+		assert.Equal(t, "user", row["name"])
+		ids = append(ids, row["id"])
+	}
+
+	assert.Equal(t, []any{int64(1), int64(2), int64(3)}, ids)
+	assert.Equal(t, []string{"SELECT * FROM `users`"}, fdb.Statements())
+	assert.Equal(t, 0, fdb.OpenRows())
+}
+
+func Test_Cursor_ClosesRowsOnBreak(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(10), nil
+	})
+
+	count := 0
+	for _, err := range xqb.Table("users").Connection(fdb.name).Cursor() {
+		assert.NoError(t, err)
+		count++
+		if count == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 0, fdb.OpenRows())
+}
+
+func Test_Cursor_YieldsQueryError(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return nil, errors.New("boom")
+	})
+
+	calls := 0
+	for row, err := range xqb.Table("users").Connection(fdb.name).Cursor() {
+		calls++
+		assert.Nil(t, row)
+		assert.ErrorIs(t, err, xqbErr.ErrQueryFailed)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func Test_ModelCursor_BindsRows(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(2), nil
+	})
+
+	var users []User
+	for user, err := range xqb.Model[User]().Connection(fdb.name).Where("id", ">", 0).Cursor() {
+		assert.NoError(t, err)
+		users = append(users, user)
+	}
+
+	assert.Len(t, users, 2)
+	assert.Equal(t, 1, users[0].ID)
+	assert.Equal(t, "user", users[1].Name)
+	assert.Equal(t, 0, fdb.OpenRows())
+}
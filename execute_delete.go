@@ -32,12 +32,7 @@ func (qb *QueryBuilder) delete(table ...string) (int64, error) {
 
 	var result sql.Result
 
-	result, err = Sql(query, args...).
-		WithContext(qb.ctx).
-		WithAfterExec(qb.settings.GetOnAfterQueryExecution()).
-		Connection(qb.connection).
-		WithTx(qb.tx).
-		Execute()
+	result, err = qb.sqlQuery(query, args).Execute()
 
 	if err != nil {
 		return 0, fmt.Errorf("delete failed:  %w", err)
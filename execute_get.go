@@ -3,7 +3,6 @@ package xqb
 import (
 	"errors"
 	"fmt"
-	"math"
 
 	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
 )
@@ -15,50 +14,37 @@ func (qb *QueryBuilder) Get() ([]map[string]any, error) {
 		return nil, fmt.Errorf("%w: Get() Failed to build the sql query, %v", xqbErr.ErrInvalidQuery, err)
 	}
 
-	rows, err := Sql(query, args...).
-		WithContext(qb.ctx).
-		WithAfterExec(qb.settings.GetOnAfterQueryExecution()).
-		Connection(qb.connection).
-		WithTx(qb.tx).
-		Query()
+	return queryAll(qb.sqlQuery(query, args), "Get()")
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("%w: Get() Invalid query sql query error %v", xqbErr.ErrQueryFailed, err)
-	}
-	defer rows.Close()
+// queryAll runs the query and scans all the rows, method prefixes the error messages
+func queryAll(sqlQuery *SqlQuery, method string) (results []map[string]any, err error) {
+	sqlQuery.withRowsCount()
+	rows, err := sqlQuery.Query()
 
-	columns, err := rows.Columns()
 	if err != nil {
-		return nil, fmt.Errorf("%w: Get() failed to retrieve columns %v", xqbErr.ErrInvalidResult, err)
+		return nil, fmt.Errorf("%w: %s Invalid query sql query error %w", xqbErr.ErrQueryFailed, method, err)
 	}
+	defer func() {
+		_ = rows.Close()
+		sqlQuery.finish(int64(len(results)), err)
+	}()
 
-	values := make([]any, len(columns))
-	valuePtrs := make([]any, len(columns))
-	for i := range values {
-		valuePtrs[i] = &values[i]
+	scanner, err := newRowScanner(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s failed to retrieve columns %v", xqbErr.ErrInvalidResult, method, err)
 	}
 
-	var results []map[string]any
 	for rows.Next() {
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("%w: Get() failed to scan result rows %v", xqbErr.ErrInvalidResult, err)
-		}
-
-		result := make(map[string]any)
-		for i, col := range columns {
-			val := values[i]
-			switch v := val.(type) {
-			case []byte:
-				result[col] = string(v)
-			default:
-				result[col] = v
-			}
+		result, scanErr := scanner.scan(rows)
+		if scanErr != nil {
+			return nil, fmt.Errorf("%w: %s failed to scan result rows %v", xqbErr.ErrInvalidResult, method, scanErr)
 		}
 		results = append(results, result)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%w: Get() failed to scan result rows %v", xqbErr.ErrInvalidResult, err)
+		return nil, fmt.Errorf("%w: %s failed to scan result rows %v", xqbErr.ErrInvalidResult, method, err)
 	}
 
 	return results, nil
@@ -120,8 +106,17 @@ func (qb *QueryBuilder) ValueSql(column string) (string, []any, error) {
 	return qb.FirstSql()
 }
 
-// Paginate returns paginated results with optional count metadata
-func (qb *QueryBuilder) Paginate(perPage int, page int, countBy string) ([]map[string]any, map[string]any, error) {
+// Paginate returns a page of results with the total count of records counted by the given column.
+// When countBy is empty the COUNT query is skipped and it behaves like SimplePaginate.
+func (qb *QueryBuilder) Paginate(perPage int, page int, countBy string) (*Paginator[map[string]any], error) {
+	if countBy == "" {
+		return qb.SimplePaginate(perPage, page)
+	}
+
+	if perPage <= 0 {
+		return nil, fmt.Errorf("%w: Paginate() per page must be greater than 0", xqbErr.ErrInvalidQuery)
+	}
+
 	if page < 1 {
 		page = 1
 	}
@@ -131,39 +126,44 @@ func (qb *QueryBuilder) Paginate(perPage int, page int, countBy string) ([]map[s
 
 	results, err := qb.Get()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	meta := map[string]any{
-		"per_page":     perPage,
-		"current_page": page,
+	copy := qb.Clone()
+	copy.resetForPaginationCount()
+	count, err := copy.Count(countBy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Paginate() failed to get count of the records: %v", xqbErr.ErrInvalidQuery, err)
 	}
 
-	if countBy != "" {
-		copy := qb.Clone()
-		copy.resetForPaginationCount()
-		count, err := copy.Count(countBy)
-		if err != nil {
-			return nil, nil, fmt.Errorf("%w: Paginate() failed to get count of the records: %v", xqbErr.ErrInvalidQuery, err)
-		}
+	return newPaginator(results, perPage, page, false).withTotal(count), nil
+}
 
-		lastPage := int(math.Ceil(float64(count) / float64(perPage)))
+// SimplePaginate returns a page of results without counting the records,
+// it fetches one extra row to know whether there are more pages.
+func (qb *QueryBuilder) SimplePaginate(perPage int, page int) (*Paginator[map[string]any], error) {
+	if perPage <= 0 {
+		return nil, fmt.Errorf("%w: SimplePaginate() per page must be greater than 0", xqbErr.ErrInvalidQuery)
+	}
 
-		var nextPage, prevPage any
-		if page < lastPage {
-			nextPage = page + 1
-		}
-		if page > 1 {
-			prevPage = page - 1
-		}
+	if page < 1 {
+		page = 1
+	}
+
+	qb.limit = perPage + 1
+	qb.offset = (page - 1) * perPage
+
+	results, err := qb.Get()
+	if err != nil {
+		return nil, err
+	}
 
-		meta["total_count"] = count
-		meta["last_page"] = lastPage
-		meta["next_page"] = nextPage
-		meta["prev_page"] = prevPage
+	hasMore := len(results) > perPage
+	if hasMore {
+		results = results[:perPage]
 	}
 
-	return results, meta, nil
+	return newPaginator(results, perPage, page, hasMore), nil
 }
 
 // Chunks processes results in batch and calls the closure for each chunk
@@ -188,7 +188,7 @@ func (qb *QueryBuilder) Chunks(chunkSize int, closure func(results []map[string]
 		}
 
 		if err := closure(results); err != nil {
-			return fmt.Errorf("%w: Chunks() failed to process chunk %v", xqbErr.ErrUnsupportedFeature, err)
+			return fmt.Errorf("Chunks() failed to process chunk: %w", err)
 		}
 
 		offset += chunkSize
@@ -197,6 +197,85 @@ func (qb *QueryBuilder) Chunks(chunkSize int, closure func(results []map[string]
 	return nil
 }
 
+// ChunkById processes results in batches using keyset pagination on the given column
+// (WHERE column > last ORDER BY column LIMIT chunkSize) instead of OFFSET.
+// The optional alias is the key name in the results, it defaults to the column without the table prefix.
+//
+// Example:
+//
+//	qb.Join("posts", "posts.user_id = users.id").ChunkById(100, "users.id", fn, "id")
+func (qb *QueryBuilder) ChunkById(chunkSize int, column string, closure func(results []map[string]any) error, alias ...string) error {
+	return qb.chunkById(chunkSize, column, false, closure, alias...)
+}
+
+// ChunkByIdDesc processes results in batches using keyset pagination on the given column in descending order
+func (qb *QueryBuilder) ChunkByIdDesc(chunkSize int, column string, closure func(results []map[string]any) error, alias ...string) error {
+	return qb.chunkById(chunkSize, column, true, closure, alias...)
+}
+
+// EachById calls the closure for each row while fetching the results in batches using keyset pagination
+func (qb *QueryBuilder) EachById(chunkSize int, column string, closure func(row map[string]any) error, alias ...string) error {
+	return qb.ChunkById(chunkSize, column, func(results []map[string]any) error {
+		for _, row := range results {
+			if err := closure(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, alias...)
+}
+
+// chunkById is the core implementation of ChunkById and ChunkByIdDesc
+func (qb *QueryBuilder) chunkById(chunkSize int, column string, descending bool, closure func(results []map[string]any) error, alias ...string) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("%w: ChunkById() chunk size must be greater than 0", xqbErr.ErrInvalidQuery)
+	}
+
+	if column == "" {
+		column = "id"
+	}
+
+	key := resultKey(column)
+	if len(alias) > 0 && alias[0] != "" {
+		key = alias[0]
+	}
+
+	var lastId any
+	for {
+		chunk := qb.Clone()
+		if descending {
+			chunk.ForPageBeforeId(chunkSize, lastId, column)
+		} else {
+			chunk.ForPageAfterId(chunkSize, lastId, column)
+		}
+
+		results, err := chunk.Get()
+		if err != nil {
+			return err
+		}
+
+		if len(results) == 0 {
+			break
+		}
+
+		if err := closure(results); err != nil {
+			return err
+		}
+
+		if len(results) < chunkSize {
+			break
+		}
+
+		id, ok := results[len(results)-1][key]
+		if !ok || id == nil {
+			return fmt.Errorf("%w: ChunkById() column %q not found in results, select it or pass its alias", xqbErr.ErrInvalidResult, key)
+		}
+		lastId = id
+	}
+
+	return nil
+}
+
 // PaginateSql returns the sql query for Paginate()
 func (qb *QueryBuilder) PaginateSql(perPage, page int) (string, []any, error) {
 	if page < 1 {
@@ -209,6 +288,18 @@ func (qb *QueryBuilder) PaginateSql(perPage, page int) (string, []any, error) {
 	return qb.ToSql()
 }
 
+// SimplePaginateSql returns the sql query for SimplePaginate()
+func (qb *QueryBuilder) SimplePaginateSql(perPage, page int) (string, []any, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	qb.limit = perPage + 1
+	qb.offset = (page - 1) * perPage
+
+	return qb.ToSql()
+}
+
 // Find finds the first result by ID
 func (qb *QueryBuilder) Find(id any) (map[string]any, error) {
 	return qb.Where("id", "=", id).First()
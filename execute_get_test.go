@@ -1,9 +1,13 @@
 package xqb_test
 
 import (
+	"database/sql/driver"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
 	"github.com/iMohamedSheta/xqb/shared/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -96,3 +100,147 @@ func Test_PaginateSql(t *testing.T) {
 		assert.Equal(t, []any{true}, bindings)
 	})
 }
+
+func Test_SimplePaginateSql(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, dialect types.Dialect) {
+		qb := xqb.Table("users").SetDialect(dialect).Where("active", "=", true)
+
+		sql, bindings, err := qb.SimplePaginateSql(10, 3)
+
+		expected := map[types.Dialect]string{
+			types.DialectMySql:    "SELECT * FROM `users` WHERE `active` = ? LIMIT 11 OFFSET 20",
+			types.DialectPostgres: `SELECT * FROM "users" WHERE "active" = $1 LIMIT 11 OFFSET 20`,
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected[dialect], sql)
+		assert.Equal(t, []any{true}, bindings)
+	})
+}
+
+func Test_Paginate_WithCount(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		if strings.Contains(query, "COUNT") {
+			return &fakeResult{columns: []string{"count"}, rows: [][]driver.Value{{int64(25)}}}, nil
+		}
+		return usersRows(10), nil
+	})
+
+	paginator, err := xqb.Table("users").Connection(fdb.name).OrderByAsc("id").Paginate(10, 2, "id")
+
+	assert.NoError(t, err)
+	assert.Len(t, paginator.Items, 10)
+	assert.Equal(t, 2, paginator.CurrentPage)
+	assert.Equal(t, int64(25), paginator.Total)
+	assert.Equal(t, 3, paginator.LastPage)
+	assert.True(t, paginator.HasTotal)
+	assert.True(t, paginator.HasMorePages())
+	assert.Equal(t, []string{
+		"SELECT * FROM `users` ORDER BY `id` ASC LIMIT 10 OFFSET 10",
+		"SELECT COUNT(`id`) AS `count` FROM `users`",
+	}, fdb.Statements())
+}
+
+func Test_SimplePaginate_SkipsCount(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(3), nil
+	})
+
+	paginator, err := xqb.Model[User]().Connection(fdb.name).SimplePaginate(2, 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, paginator.Items, 2)
+	assert.Equal(t, 1, paginator.Items[0].ID)
+	assert.False(t, paginator.HasTotal)
+	assert.True(t, paginator.HasMorePages())
+	assert.Equal(t, []string{"SELECT * FROM `users` LIMIT 3"}, fdb.Statements())
+}
+
+// keysetHandler serves the ids 1..total in pages keyed by the last id binding
+func keysetHandler(total int64, pageSize int64) fakeHandler {
+	return func(query string, args []any) (*fakeResult, error) {
+		var after int64
+		if len(args) > 0 {
+			after = args[len(args)-1].(int64)
+		}
+
+		res := &fakeResult{columns: []string{"id"}}
+		for id := after + 1; id <= total && id <= after+pageSize; id++ {
+			res.rows = append(res.rows, []driver.Value{id})
+		}
+		return res, nil
+	}
+}
+
+func Test_ChunkById(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, keysetHandler(5, 2))
+
+	var chunks [][]any
+	err := xqb.Table("users").Connection(fdb.name).ChunkById(2, "id", func(results []map[string]any) error {
+		var ids []any
+		for _, row := range results {
+			ids = append(ids, row["id"])
+		}
+		chunks = append(chunks, ids)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{int64(1), int64(2)}, {int64(3), int64(4)}, {int64(5)}}, chunks)
+	assert.Equal(t, []string{
+		"SELECT * FROM `users` ORDER BY `id` ASC LIMIT 2",
+		"SELECT * FROM `users` WHERE `id` > ? ORDER BY `id` ASC LIMIT 2",
+		"SELECT * FROM `users` WHERE `id` > ? ORDER BY `id` ASC LIMIT 2",
+	}, fdb.Statements())
+}
+
+func Test_ChunkById_ReturnsClosureError(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, keysetHandler(5, 2))
+	stop := errors.New("stop")
+
+	err := xqb.Table("users").Connection(fdb.name).ChunkById(2, "id", func(results []map[string]any) error {
+		return stop
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.Len(t, fdb.Statements(), 1)
+}
+
+func Test_Chunks_ReturnsClosureError(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(2), nil
+	})
+	stop := errors.New("stop")
+
+	err := xqb.Table("users").Connection(fdb.name).Chunks(2, func(results []map[string]any) error {
+		return stop
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.NotErrorIs(t, err, xqbErr.ErrUnsupportedFeature)
+	assert.Len(t, fdb.Statements(), 1)
+}
+
+func Test_ChunkById_MissingAlias(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, keysetHandler(5, 2))
+
+	err := xqb.Table("users").Connection(fdb.name).ChunkById(2, "users.id", func(results []map[string]any) error {
+		return nil
+	}, "user_id")
+
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidResult)
+}
+
+func Test_ModelEachById(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, keysetHandler(3, 2))
+
+	var ids []int
+	err := xqb.Model[User]().Connection(fdb.name).SetDialect(types.DialectPostgres).EachById(2, "users.id", func(user User) error {
+		ids = append(ids, user.ID)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ids)
+	assert.Equal(t, `SELECT * FROM "users" WHERE "users"."id" > $1 ORDER BY "users"."id" ASC LIMIT 2`, fdb.Statements()[1])
+}
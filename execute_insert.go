@@ -35,12 +35,7 @@ func (qb *QueryBuilder) Upsert(values []map[string]any, uniqueBy []string, updat
 		return 0, err
 	}
 
-	result, err := Sql(query, args...).
-		WithContext(qb.ctx).
-		WithAfterExec(qb.settings.GetOnAfterQueryExecution()).
-		Connection(qb.connection).
-		WithTx(qb.tx).
-		Execute()
+	result, err := qb.sqlQuery(query, args).Execute()
 
 	if err != nil {
 		return 0, err
@@ -107,12 +102,8 @@ func (qb *QueryBuilder) insert(values []map[string]any, getId bool) (sql.Result,
 	}
 
 	if getId && qb.dialect.Getdialect().String() == types.DialectPostgres.String() {
-		rows, err := Sql(query, args...).
-			WithContext(qb.ctx).
-			WithAfterExec(qb.settings.GetOnAfterQueryExecution()).
-			Connection(qb.connection).
-			WithTx(qb.tx).
-			Query()
+		sqlQuery := qb.sqlQuery(query, args).withRowsCount()
+		rows, err := sqlQuery.Query()
 
 		if err != nil {
 			return nil, err
@@ -124,10 +115,12 @@ func (qb *QueryBuilder) insert(values []map[string]any, getId bool) (sql.Result,
 		for rows.Next() {
 			var id int64
 			if err := rows.Scan(&id); err != nil {
+				sqlQuery.finish(int64(len(ids)), err)
 				return nil, err
 			}
 			ids = append(ids, id)
 		}
+		sqlQuery.finish(int64(len(ids)), rows.Err())
 
 		affectedRows := int64(len(ids))
 
@@ -137,12 +130,7 @@ func (qb *QueryBuilder) insert(values []map[string]any, getId bool) (sql.Result,
 		}, nil
 	}
 
-	return Sql(query, args...).
-		WithContext(qb.ctx).
-		WithAfterExec(qb.settings.GetOnAfterQueryExecution()).
-		Connection(qb.connection).
-		WithTx(qb.tx).
-		Execute()
+	return qb.sqlQuery(query, args).Execute()
 }
 
 type QuerResult struct {
@@ -3,29 +3,47 @@ package xqb
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iMohamedSheta/xqb/dialects"
+	"github.com/iMohamedSheta/xqb/shared/enums"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
 )
 
 type SqlQuery struct {
+	manager    *DBM
 	connection string
 	tx         *sql.Tx
 	sql        string
 	args       []any
 	ctx        context.Context
 	afterExec  func(context.Context)
+	withoutTx  bool
+	usePrimary bool
+	cache      *queryCache
+	settings   *QueryBuilderSettings
+
+	// execution event metadata set by the query builder
+	queryType enums.QueryType
+	table     string
+	locked    bool
+	countRows bool
+	started   time.Time
+	pending   *QueryExecution
 }
 
+// Sql creates a raw sql query on the default connection of the default manager
 func Sql(sql string, args ...any) *SqlQuery {
-	return &SqlQuery{
-		connection: "default",
-		sql:        sql,
-		args:       args,
-	}
+	return DBManager().Sql(sql, args...)
 }
 
-// Connection - set the connection
+// Connection - set the connection, unknown connections fall back to the default connection of the manager
 func (s *SqlQuery) Connection(connection string) *SqlQuery {
-	if connection == "" || !DBManager().HasConnection(connection) {
-		connection = "default"
+	if connection == "" || !s.getManager().HasConnection(connection) {
+		connection = s.getManager().GetDefaultConnectionName()
 	}
 	s.connection = connection
 	return s
@@ -41,32 +59,55 @@ func (s *SqlQuery) WithAfterExec(f func(ctx context.Context)) *SqlQuery {
 	return s
 }
 
+// WithSettings - set the settings providing the interceptors (the manager settings by default)
+func (s *SqlQuery) WithSettings(settings *QueryBuilderSettings) *SqlQuery {
+	s.settings = settings
+	return s
+}
+
 // WithTx - set the transaction
 func (s *SqlQuery) WithTx(tx *sql.Tx) *SqlQuery {
 	s.tx = tx
 	return s
 }
 
-// ExecuteSql - execute raw sql statement
-func (s *SqlQuery) Execute() (sql.Result, error) {
-	var (
-		result sql.Result
-		err    error
-	)
+// WithoutTx - run the query outside the transaction propagated through the context
+func (s *SqlQuery) WithoutTx() *SqlQuery {
+	s.tx = nil
+	s.withoutTx = true
+	return s
+}
 
-	if s.ctx == nil {
-		s.ctx = context.Background()
-	}
+// UsePrimary - run the query on the primary even if it's a read the connection replicas could serve
+func (s *SqlQuery) UsePrimary() *SqlQuery {
+	s.usePrimary = true
+	return s
+}
 
+// activeTx returns the transaction set with WithTx or the one propagated through the context for the query connection
+func (s *SqlQuery) activeTx() *sql.Tx {
 	if s.tx != nil {
-		result, err = s.tx.ExecContext(s.ctx, s.sql, s.args...)
-	} else {
-		db, errConn := GetConnectionDB(s.connection)
-		if errConn != nil {
-			return nil, errConn
+		return s.tx
+	}
+	if s.withoutTx {
+		return nil
+	}
+	tx, _ := txFromContext(s.ctx, s.getManager(), s.connection)
+	return tx
+}
+
+// ExecuteSql - execute raw sql statement
+func (s *SqlQuery) Execute() (sql.Result, error) {
+	event, result, err := s.run(QueryKindExecute)
+
+	execution := s.newExecution(event)
+	execution.Err = err
+	if err == nil {
+		if affected, rowsErr := result.Exec.RowsAffected(); rowsErr == nil {
+			execution.Rows = affected
 		}
-		result, err = db.ExecContext(s.ctx, s.sql, s.args...)
 	}
+	s.emitExecution(execution)
 
 	if s.afterExec != nil {
 		safeCall(func() {
@@ -74,28 +115,20 @@ func (s *SqlQuery) Execute() (sql.Result, error) {
 		})
 	}
 
-	return result, err
+	return result.Exec, err
 }
 
 // QuerySql - query raw sql statement
 func (s *SqlQuery) Query() (*sql.Rows, error) {
-	if s.ctx == nil {
-		s.ctx = context.Background()
-	}
+	event, result, err := s.run(QueryKindQuery)
 
-	var (
-		rows *sql.Rows
-		err  error
-	)
-
-	if s.tx != nil {
-		rows, err = s.tx.QueryContext(s.ctx, s.sql, s.args...)
+	execution := s.newExecution(event)
+	execution.Err = err
+	if s.countRows && err == nil {
+		// the builder reads the rows then emits the event with finish
+		s.pending = execution
 	} else {
-		db, errConn := GetConnectionDB(s.connection)
-		if errConn != nil {
-			return nil, errConn
-		}
-		rows, err = db.QueryContext(s.ctx, s.sql, s.args...)
+		s.emitExecution(execution)
 	}
 
 	if s.afterExec != nil {
@@ -104,38 +137,232 @@ func (s *SqlQuery) Query() (*sql.Rows, error) {
 		})
 	}
 
-	return rows, err
+	return result.Rows, err
 }
 
 // QueryRow - query raw sql statement and scan the result into the pointed dest variable
 // Example: Sql("SELECT * FROM users WHERE id = ?", 1).QueryRow(&user)
 func (s *SqlQuery) QueryRow(dest ...any) error {
+	event, result, err := s.run(QueryKindQueryRow)
+
+	// don't return error before running afterExec hook
+	if err == nil {
+		err = scanRow(result.Rows, dest...)
+	}
+
+	execution := s.newExecution(event)
+	execution.Err = err
+	switch {
+	case err == nil:
+		execution.Rows = 1
+	case errors.Is(err, sql.ErrNoRows):
+		execution.Rows = 0
+	}
+	s.emitExecution(execution)
+
+	if s.afterExec != nil {
+		safeCall(func() {
+			s.afterExec(s.ctx)
+		})
+	}
+
+	return err
+}
+
+// run executes the statement through the interceptor chain of the settings
+func (s *SqlQuery) run(kind QueryKind) (*QueryEvent, Result, error) {
 	if s.ctx == nil {
 		s.ctx = context.Background()
 	}
+	s.started = time.Now()
 
-	var row *sql.Row
+	event := &QueryEvent{
+		Kind:       kind,
+		Sql:        s.sql,
+		Bindings:   s.args,
+		Connection: s.connection,
+		Tx:         s.activeTx(),
+		manager:    s.getManager(),
+	}
+	event.Replica = event.Tx == nil && s.isReplicaRead(kind)
 
-	if s.tx != nil {
-		row = s.tx.QueryRowContext(s.ctx, s.sql, s.args...)
-	} else {
-		db, err := GetConnectionDB(s.connection)
+	// each round trip has its own span so a short-circuiting interceptor doesn't start one
+	// and an interceptor calling next more than once gets one span per call
+	execute := func(ctx context.Context, event *QueryEvent) (Result, error) {
+		ctx, span := s.startQuerySpan(ctx, event)
+		result, err := executeEvent(ctx, event)
+		endQuerySpan(span, result, err)
+		return result, err
+	}
+	if s.isCacheable(kind, event) {
+		execute = s.cached(execute)
+	}
+
+	result, err := chainInterceptors(s.getSettings().GetInterceptors(), execute)(s.ctx, event)
+	if err != nil {
+		return event, result, err
+	}
+
+	if s.isWrite(kind) {
+		markWrite(s.ctx, s.connection)
+		s.invalidateTableCache(event)
+	}
+
+	// an interceptor short-circuiting the chain must return the result of the query kind
+	if (kind == QueryKindExecute && result.Exec == nil) || (kind != QueryKindExecute && result.Rows == nil) {
+		return event, Result{}, fmt.Errorf("%w: %s interceptor returned an empty result", xqbErr.ErrInvalidResult, kind)
+	}
+
+	return event, result, err
+}
+
+// isReplicaRead reports whether the statement is a read without locks the connection replicas can serve
+func (s *SqlQuery) isReplicaRead(kind QueryKind) bool {
+	if kind == QueryKindExecute || s.usePrimary {
+		return false
+	}
+	if s.queryType != 0 {
+		return s.queryType == enums.SELECT && !s.locked
+	}
+	return isReplicaRead(s.sql)
+}
+
+// isWrite reports whether the statement writes, the following reads of a sticky context use the primary
+func (s *SqlQuery) isWrite(kind QueryKind) bool {
+	queryType := s.queryType
+	if queryType == 0 {
+		queryType = inferQueryType(s.sql)
+	}
+	return kind == QueryKindExecute || queryType == enums.INSERT || queryType == enums.UPDATE || queryType == enums.DELETE
+}
+
+// getManager returns the manager the query was created from or the default manager
+func (s *SqlQuery) getManager() *DBM {
+	if s.manager != nil {
+		return s.manager
+	}
+	return DBManager()
+}
+
+// getSettings returns the settings set with WithSettings or the settings of the manager
+func (s *SqlQuery) getSettings() *QueryBuilderSettings {
+	if s.settings != nil {
+		return s.settings
+	}
+	return s.getManager().GetSettings()
+}
+
+// executeEvent is the last step of the interceptor chain, it runs the statement on the database
+func executeEvent(ctx context.Context, event *QueryEvent) (Result, error) {
+	manager := event.getManager()
+	result, err := manager.execute(ctx, event)
+	return result, manager.translateError(event.Connection, err)
+}
+
+// queryRunner runs the statements on a *sql.DB or a *sql.Tx
+type queryRunner interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// execute runs the event in its transaction or on the database of the connection,
+// through the prepared statement cache when the connection enables it
+func (m *DBM) execute(ctx context.Context, event *QueryEvent) (Result, error) {
+	var (
+		runner queryRunner = event.Tx
+		db     *sql.DB
+		err    error
+	)
+
+	if event.Tx == nil {
+		db, err = m.eventDB(ctx, event)
 		if err != nil {
+			return Result{}, err
+		}
+		runner = db
+		event.db = db
+	}
+
+	if conn, connErr := m.Connection(event.Connection); connErr == nil {
+		if cache := conn.statements(); cache != nil {
+			if db == nil {
+				// the transaction statements are prepared on the primary then bound with tx.StmtContext
+				db = conn.DB
+			}
+			return cache.execute(ctx, db, event)
+		}
+	}
+
+	var result Result
+	if event.Kind == QueryKindExecute {
+		result.Exec, err = runner.ExecContext(ctx, event.Sql, event.Bindings...)
+	} else {
+		result.Rows, err = runner.QueryContext(ctx, event.Sql, event.Bindings...)
+	}
+	return result, err
+}
+
+// scanRow scans the first row into dest and closes the rows, sql.ErrNoRows is returned when there is no row
+func scanRow(rows *sql.Rows, dest ...any) error {
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
 			return err
 		}
-		row = db.QueryRowContext(s.ctx, s.sql, s.args...)
+		return sql.ErrNoRows
 	}
 
-	// don't return error before running afterExec hook
-	err := row.Scan(dest...)
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
 
-	if s.afterExec != nil {
-		safeCall(func() {
-			s.afterExec(s.ctx)
-		})
+	return rows.Close()
+}
+
+// sqlQuery creates the SqlQuery executing the compiled query with the builder context, connection and transaction
+func (qb *QueryBuilder) sqlQuery(query string, args []any) *SqlQuery {
+	s := qb.getManager().Sql(query, args...).
+		WithContext(qb.ctx).
+		WithSettings(qb.GetSettings()).
+		WithAfterExec(qb.GetSettings().GetOnAfterQueryExecution()).
+		Connection(qb.connection).
+		WithTx(qb.tx)
+	s.queryType = qb.queryType
+	if qb.table != nil {
+		s.table = qb.table.Name
 	}
+	_, s.locked = qb.GetOption(types.OptionLock)
+	if qb.usePrimary {
+		s.UsePrimary()
+	}
+	s.cache = qb.cache
 
-	return err
+	if qb.withoutTx {
+		s.WithoutTx()
+	}
+
+	return s
+}
+
+// withRowsCount defers the execution event of Query until the builder read the rows and called finish
+func (s *SqlQuery) withRowsCount() *SqlQuery {
+	s.countRows = true
+	return s
+}
+
+// translateError converts a driver error into a *DBError with the dialect of the connection
+func (m *DBM) translateError(connection string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	dialect, dialectErr := m.GetDialect(connection)
+	if dialectErr != nil {
+		return err
+	}
+
+	return dialects.GetDialect(dialect.MappedDialect()).TranslateError(err)
 }
 
 // Use safeCall to avoid panic if the callback function panics
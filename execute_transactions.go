@@ -1,64 +1,330 @@
 package xqb
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/iMohamedSheta/xqb/dialects"
 	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
 )
 
 // BeginTx starts a transaction using the default connection.
 func BeginTx() (*sql.Tx, error) {
-	return BeginTxOn("default")
+	return DBManager().BeginTx()
 }
 
 // BeginTxOn starts a transaction using the specified connection.
 func BeginTxOn(connection string) (*sql.Tx, error) {
-	if !DBManager().HasConnection(connection) {
+	return DBManager().BeginTxOn(connection)
+}
+
+// BeginTx starts a transaction using the default connection of the manager.
+func (m *DBM) BeginTx() (*sql.Tx, error) {
+	return m.BeginTxOn(m.GetDefaultConnectionName())
+}
+
+// BeginTxOn starts a transaction using the specified connection of the manager.
+// Use BeginTxHandleOn for a handle running the AfterCommit/AfterRollback callbacks.
+func (m *DBM) BeginTxOn(connection string) (*sql.Tx, error) {
+	conn, err := m.Connection(connection)
+	if err != nil || conn.DB == nil {
 		return nil, fmt.Errorf("%w: invalid connection %s", xqbErr.ErrNoConnection, connection)
 	}
+	return conn.DB.Begin()
+}
+
+// BeginTxHandle starts a transaction using the default connection and returns its handle (see Tx).
+func BeginTxHandle() (*Tx, error) {
+	return DBManager().BeginTxHandle()
+}
+
+// BeginTxHandleOn starts a transaction using the specified connection and returns its handle (see Tx).
+func BeginTxHandleOn(connection string) (*Tx, error) {
+	return DBManager().BeginTxHandleOn(connection)
+}
+
+// BeginTxHandle starts a transaction using the default connection of the manager and returns its handle.
+func (m *DBM) BeginTxHandle() (*Tx, error) {
+	return m.BeginTxHandleOn(m.GetDefaultConnectionName())
+}
+
+// BeginTxHandleOn starts a transaction using the specified connection of the manager and returns its handle.
+func (m *DBM) BeginTxHandleOn(connection string) (*Tx, error) {
+	return m.beginTxCtx(context.Background(), connection, nil)
+}
 
-	db, err := DBManager().ConnectionDB(connection)
+// Transaction runs a function inside a transaction on the default connection.
+func Transaction(fn func(*sql.Tx) error) error {
+	return DBManager().Transaction(fn)
+}
+
+// TransactionOn runs a function inside a transaction on the given connection.
+// It always starts a new transaction, nest in a running one with NestedTransaction or with
+// TransactionCtxOn and the context of the running transaction.
+func TransactionOn(connection string, fn func(*sql.Tx) error) error {
+	return DBManager().TransactionOn(connection, fn)
+}
+
+// Transaction runs a function inside a transaction on the default connection of the manager.
+func (m *DBM) Transaction(fn func(*sql.Tx) error) error {
+	return m.TransactionOn(m.GetDefaultConnectionName(), fn)
+}
+
+// TransactionOn runs a function inside a transaction on the given connection of the manager.
+func (m *DBM) TransactionOn(connection string, fn func(*sql.Tx) error) error {
+	return m.TransactionCtxOn(context.Background(), connection, nil, func(_ context.Context, tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
+
+// panicError converts a panic recovered inside a transaction function into an error
+func panicError(p any) error {
+	switch e := p.(type) {
+	case error:
+		return fmt.Errorf("%w: %v", xqbErr.ErrTransactionFailed, e)
+	default:
+		return fmt.Errorf("%w: panic %v", xqbErr.ErrTransactionFailed, p)
+	}
+}
+
+// TxOptions holds the isolation level, read only mode, deferrable mode and timeout of a transaction
+type TxOptions = types.TxOptions
+
+// BeginTxCtx starts a transaction bound to the context on the default connection.
+func BeginTxCtx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	return DBManager().BeginTxCtx(ctx, opts)
+}
+
+// BeginTxCtxOn starts a transaction bound to the context on the given connection.
+// database/sql rolls the transaction back when the context is canceled or the timeout expires before Commit.
+func BeginTxCtxOn(ctx context.Context, connection string, opts *TxOptions) (*Tx, error) {
+	return DBManager().BeginTxCtxOn(ctx, connection, opts)
+}
+
+// BeginTxCtx starts a transaction bound to the context on the default connection of the manager.
+func (m *DBM) BeginTxCtx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	return m.BeginTxCtxOn(ctx, m.GetDefaultConnectionName(), opts)
+}
+
+// BeginTxCtxOn starts a transaction bound to the context on the given connection of the manager (see BeginTxCtxOn).
+func (m *DBM) BeginTxCtxOn(ctx context.Context, connection string, opts *TxOptions) (*Tx, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if opts != nil && opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		// the transaction outlives this call so the timeout cancels the context instead of a deferred cancel
+		timer := time.AfterFunc(opts.Timeout, cancel)
+
+		stop := func() {
+			timer.Stop()
+			cancel()
+		}
+
+		tx, err := m.beginTxCtx(ctx, connection, opts)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		tx.stop = stop
+		return tx, nil
+	}
+
+	return m.beginTxCtx(ctx, connection, opts)
+}
+
+// beginTxCtx starts the transaction with the driver options then runs the dialect statements for the remaining options.
+// The transaction span of the manager settings tracer is started and ended when the transaction finishes.
+func (m *DBM) beginTxCtx(ctx context.Context, connection string, opts *TxOptions) (*Tx, error) {
+	conn, err := m.Connection(connection)
+	if err != nil || conn.DB == nil {
+		return nil, fmt.Errorf("%w: invalid connection %s", xqbErr.ErrNoConnection, connection)
+	}
+
+	var txOptions *sql.TxOptions
+	if opts != nil {
+		txOptions = &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	}
+
+	statements, err := dialects.GetDialect(conn.Dialect.MappedDialect()).CompileTransactionOptions(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	tx, err := db.Begin()
+	attributes := map[string]any{
+		"db.system":      m.dbSystem(connection),
+		"xqb.connection": connection,
+	}
+	if txOptions != nil {
+		attributes["xqb.transaction.isolation"] = txOptions.Isolation.String()
+		attributes["xqb.transaction.read_only"] = txOptions.ReadOnly
+	}
+	ctx, span := startSpan(ctx, m.GetSettings().GetTracer(), "TRANSACTION", attributes)
+
+	tx, err := conn.DB.BeginTx(ctx, txOptions)
 	if err != nil {
+		span.RecordError(err)
+		span.End()
 		return nil, err
 	}
 
-	return tx, nil
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			_ = tx.Rollback()
+			err = fmt.Errorf("%w: failed to apply transaction options %q: %v", xqbErr.ErrTransactionFailed, statement, err)
+			span.RecordError(err)
+			span.End()
+			return nil, err
+		}
+	}
+
+	handle := trackTx(m, tx, connection, ctx)
+	handle.span = span
+
+	return handle, nil
 }
 
-// Transaction runs a function inside a transaction on the default connection.
-func Transaction(fn func(*sql.Tx) error) error {
-	return TransactionOn("default", fn)
+// TransactionCtx runs a function inside a transaction bound to the context on the default connection.
+func TransactionCtx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return DBManager().TransactionCtx(ctx, opts, fn)
 }
 
-// TransactionOn runs a function inside a transaction on the given connection.
-func TransactionOn(connection string, fn func(*sql.Tx) error) (err error) {
-	tx, err := BeginTxOn(connection)
+// TransactionCtxOn runs a function inside a transaction bound to the context on the given connection.
+// The function receives the transaction context (including the timeout) carrying the transaction,
+// queries built with WithContext(ctx) on the connection run inside it without WithTx(tx).
+// When the context already carries a transaction of the connection the function runs in a savepoint
+// of it (see NestedTransaction) and only the timeout option applies, ContextWithoutTx starts a new one.
+// The transaction is rolled back when the function fails, panics or the context is done before commit.
+//
+// Example:
+//
+//	err := xqb.TransactionCtxOn(ctx, "default", &xqb.TxOptions{Isolation: sql.LevelSerializable, Timeout: 5 * time.Second},
+//		func(ctx context.Context, tx *sql.Tx) error {
+//			_, err := xqb.Table("accounts").WithContext(ctx).Where("id", "=", 1).Update(map[string]any{"balance": 0})
+//			return err
+//		})
+func TransactionCtxOn(ctx context.Context, connection string, opts *TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return DBManager().TransactionCtxOn(ctx, connection, opts, fn)
+}
+
+// TransactionCtx runs a function inside a transaction bound to the context on the default connection of the manager.
+func (m *DBM) TransactionCtx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return m.TransactionCtxOn(ctx, m.GetDefaultConnectionName(), opts, fn)
+}
+
+// TransactionCtxOn runs a function inside a transaction bound to the context on the given connection of the manager (see TransactionCtxOn).
+func (m *DBM) TransactionCtxOn(ctx context.Context, connection string, opts *TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if opts != nil && opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	if outer, ok := txHandleFromContext(ctx, m, connection); ok {
+		ctx = contextWithTxHandle(ctx, outer)
+		return nestedTransaction(ctx, outer.Tx, func(tx *sql.Tx) error {
+			return fn(ctx, tx)
+		})
+	}
+
+	tx, err := m.beginTxCtx(ctx, connection, opts)
 	if err != nil {
 		return err
 	}
+	ctx = contextWithTxHandle(tx.ctx, tx)
 
 	defer func() {
 		if p := recover(); p != nil {
 			_ = tx.Rollback()
-			switch e := p.(type) {
-			case error:
-				err = fmt.Errorf("%w: %v", xqbErr.ErrTransactionFailed, e)
-			default:
-				err = fmt.Errorf("%w: panic %v", xqbErr.ErrTransactionFailed, p)
-			}
+			err = panicError(p)
 		}
 	}()
 
-	if err := fn(tx); err != nil {
+	if err := fn(ctx, tx.Tx); err != nil {
 		_ = tx.Rollback()
 		return err
 	}
 
-	return tx.Commit()
+	if ctx.Err() != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%w: transaction context is done: %w", xqbErr.ErrTransactionFailed, ctx.Err())
+	}
+
+	if err := tx.Commit(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: transaction context is done: %w", xqbErr.ErrTransactionFailed, ctx.Err())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// NestedTransaction runs a function inside the given transaction using a savepoint.
+// A failure rolls back to the savepoint (ROLLBACK TO SAVEPOINT sp_n) leaving the outer transaction usable,
+// a success releases it (RELEASE SAVEPOINT sp_n). When tx is nil the function runs in Transaction() on the
+// default connection of the default manager, so functions can be composed without knowing whether
+// a transaction is already open.
+//
+// Example:
+//
+//	func CreateUser(tx *sql.Tx, user map[string]any) error {
+//		return xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+//			return xqb.Table("users").WithTx(tx).Insert([]map[string]any{user})
+//		})
+//	}
+func NestedTransaction(tx *sql.Tx, fn func(*sql.Tx) error) error {
+	if tx == nil {
+		return DBManager().Transaction(fn)
+	}
+
+	ctx := context.Background()
+	if handle, ok := LookupTx(tx); ok && handle.ctx != nil {
+		ctx = handle.ctx
+	}
+	return nestedTransaction(ctx, tx, fn)
+}
+
+// nestedTransaction runs the function in a savepoint of tx, the savepoint statements are bound to the context
+func nestedTransaction(ctx context.Context, tx *sql.Tx, fn func(*sql.Tx) error) (err error) {
+	handle, savepoint := enterSavepoint(tx)
+	opened, released := false, false
+	defer func() {
+		handle.leaveSavepoint(opened, released)
+	}()
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("%w: failed to create savepoint %s: %v", xqbErr.ErrTransactionFailed, savepoint, err)
+	}
+	opened = true
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			err = panicError(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			return fmt.Errorf("%w: failed to rollback to savepoint %s: %v (%w)", xqbErr.ErrTransactionFailed, savepoint, rbErr, err)
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("%w: failed to release savepoint %s: %v", xqbErr.ErrTransactionFailed, savepoint, err)
+	}
+	released = true
+
+	return nil
 }
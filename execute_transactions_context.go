@@ -0,0 +1,79 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// txContextKey is the context key of the transaction propagated through context.Context
+type txContextKey struct{}
+
+// txContextValue holds the propagated transaction handle and the connection it belongs to, a nil value opts out
+type txContextValue struct {
+	tx         *Tx
+	connection string
+}
+
+// ContextWithTx returns a copy of the context carrying the transaction of the given connection.
+// Queries built with WithContext(ctx) on the same connection run inside the transaction without WithTx(tx).
+// TransactionCtx and TransactionCtxOn already pass such a context to their function.
+func ContextWithTx(ctx context.Context, connection string, tx *sql.Tx) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	handle, ok := LookupTx(tx)
+	if !ok {
+		handle = &Tx{Tx: tx, connection: connection, scopes: []*txScope{{}}}
+	}
+	return context.WithValue(ctx, txContextKey{}, &txContextValue{tx: handle, connection: connection})
+}
+
+// contextWithTxHandle returns a copy of the context carrying the transaction handle
+func contextWithTxHandle(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, &txContextValue{tx: tx, connection: tx.connection})
+}
+
+// ContextWithoutTx returns a copy of the context that doesn't carry a transaction,
+// queries built with it run outside the transaction propagated by the parent context.
+func ContextWithoutTx(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, txContextKey{}, (*txContextValue)(nil))
+}
+
+// TxFromContext returns the handle of the transaction carried by the context
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	value := txValueFromContext(ctx)
+	if value == nil || value.tx == nil {
+		return nil, false
+	}
+	return value.tx, true
+}
+
+// txFromContext returns the transaction carried by the context when it belongs to the given connection of the manager
+func txFromContext(ctx context.Context, manager *DBM, connection string) (*sql.Tx, bool) {
+	handle, ok := txHandleFromContext(ctx, manager, connection)
+	if !ok {
+		return nil, false
+	}
+	return handle.Tx, true
+}
+
+// txHandleFromContext returns the handle of the transaction carried by the context when it belongs to the
+// given connection of the manager
+func txHandleFromContext(ctx context.Context, manager *DBM, connection string) (*Tx, bool) {
+	value := txValueFromContext(ctx)
+	if value == nil || value.tx == nil || value.connection != connection || value.tx.getManager() != manager {
+		return nil, false
+	}
+	return value.tx, true
+}
+
+func txValueFromContext(ctx context.Context) *txContextValue {
+	if ctx == nil {
+		return nil
+	}
+	value, _ := ctx.Value(txContextKey{}).(*txContextValue)
+	return value
+}
@@ -0,0 +1,96 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TransactionCtxOn_PropagatesTxThroughContext(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		ctxTx, ok := xqb.TxFromContext(ctx)
+		assert.True(t, ok)
+		assert.Same(t, tx, ctxTx.Tx)
+
+		if _, err := xqb.Table("users").Connection(fdb.name).WithContext(ctx).Where("id", "=", 1).Delete(); err != nil {
+			return err
+		}
+		if _, err := xqb.Model[User]().Connection(fdb.name).WithContext(ctx).Get(); err != nil {
+			return err
+		}
+		if _, err := xqb.Sql("UPDATE users SET name = ?", "john").Connection(fdb.name).WithContext(ctx).Execute(); err != nil {
+			return err
+		}
+
+		// opt-outs run outside the transaction
+		if _, err := xqb.Table("logs").Connection(fdb.name).WithContext(ctx).WithoutTx().Get(); err != nil {
+			return err
+		}
+		if _, err := xqb.Sql("SELECT 1").Connection(fdb.name).WithContext(ctx).WithoutTx().Query(); err != nil {
+			return err
+		}
+		_, err := xqb.Table("audits").Connection(fdb.name).WithContext(xqb.ContextWithoutTx(ctx)).Get()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"DELETE FROM `users` WHERE `id` = ?",
+		"SELECT * FROM `users`",
+		"UPDATE users SET name = ?",
+	}, fdb.TxStatements())
+	assert.Len(t, fdb.Statements(), 8)
+}
+
+func Test_TransactionCtxOn_IgnoresTxOfOtherConnection(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	other := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := xqb.Table("users").Connection(other.name).WithContext(ctx).Get()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SELECT * FROM `users`"}, other.Statements())
+	assert.Empty(t, other.TxStatements())
+}
+
+func Test_TransactionCtxOn_NestsWithSavepoint(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, outer *sql.Tx) error {
+		return xqb.TransactionCtxOn(ctx, fdb.name, nil, func(ctx context.Context, inner *sql.Tx) error {
+			assert.Same(t, outer, inner)
+			_, err := xqb.Table("users").Connection(fdb.name).WithContext(ctx).Where("id", "=", 1).Delete()
+			return err
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SAVEPOINT sp_1",
+		"DELETE FROM `users` WHERE `id` = ?",
+		"RELEASE SAVEPOINT sp_1",
+		"COMMIT",
+	}, fdb.Statements())
+}
+
+func Test_ChunksParallel_RejectsContextTx(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return xqb.Table("users").Connection(fdb.name).WithContext(ctx).ChunksParallel(10, 2, func([]map[string]any) error {
+			return nil
+		})
+	})
+
+	assert.ErrorIs(t, err, xqbErr.ErrUnsupportedFeature)
+}
@@ -0,0 +1,290 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// Tx is a transaction handle wrapping *sql.Tx. Commit and Rollback run the callbacks registered
+// with AfterCommit and AfterRollback, pass tx.Tx to WithTx() to run queries inside the transaction.
+// The handle is returned by BeginTxHandle and the BeginTxCtx functions, found through the context of
+// TransactionCtx (see TxFromContext) or looked up from the *sql.Tx given to the Transaction functions (see LookupTx).
+// The callbacks only run when the transaction is finished through the handle, a transaction finished
+// through the embedded *sql.Tx discards them.
+type Tx struct {
+	*sql.Tx
+	manager    *DBM
+	connection string
+
+	mu      sync.Mutex
+	scopes  []*txScope // the transaction scope then one scope per open savepoint
+	owned   bool       // started by xqb, removed from the registry on Commit/Rollback
+	done    bool
+	outcome txOutcome
+
+	span    Span            // the transaction span ended on Commit/Rollback
+	ctx     context.Context // the context carrying the transaction span
+	stop    func()          // stops the timeout timer and cancels the context of BeginTxCtx on Commit/Rollback
+	untrack func() bool     // stops the removal from the registry when the context is done
+}
+
+// txOutcome is how a transaction handle finished
+type txOutcome int
+
+const (
+	txRolledBack txOutcome = iota
+	txCommitted
+	txFinishedOutside // finished through the embedded *sql.Tx, committed or rolled back
+)
+
+// txScope holds the callbacks registered in the transaction or in one of its savepoints
+type txScope struct {
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+// activeTx holds the handles weakly: a transaction finished through its *sql.Tx is forgotten
+// once nothing references its handle anymore
+var (
+	activeTxMu sync.Mutex
+	activeTx   = make(map[*sql.Tx]weak.Pointer[Tx])
+)
+
+// trackTx creates the handle of a transaction started by xqb and registers it until Commit/Rollback
+// or until its context is done
+func trackTx(manager *DBM, tx *sql.Tx, connection string, ctx context.Context) *Tx {
+	handle := &Tx{Tx: tx, manager: manager, connection: connection, scopes: []*txScope{{}}, owned: true, ctx: ctx}
+	registerTx(handle)
+	handle.untrack = context.AfterFunc(ctx, handle.forget)
+
+	return handle
+}
+
+// registerTx adds the handle to the registry, the entry is removed when the handle is garbage collected
+func registerTx(handle *Tx) {
+	activeTxMu.Lock()
+	activeTx[handle.Tx] = weak.Make(handle)
+	activeTxMu.Unlock()
+
+	runtime.AddCleanup(handle, forgetCollectedTx, handle.Tx)
+}
+
+// forgetCollectedTx removes the registry entry of a garbage collected handle
+func forgetCollectedTx(tx *sql.Tx) {
+	activeTxMu.Lock()
+	defer activeTxMu.Unlock()
+
+	if pointer, ok := activeTx[tx]; ok && pointer.Value() == nil {
+		delete(activeTx, tx)
+	}
+}
+
+// forget removes the handle from the registry
+func (t *Tx) forget() {
+	activeTxMu.Lock()
+	defer activeTxMu.Unlock()
+
+	if pointer, ok := activeTx[t.Tx]; ok && pointer.Value() == t {
+		delete(activeTx, t.Tx)
+	}
+}
+
+// LookupTx returns the handle of a running transaction, it's found for the transactions started by xqb
+// until they finish or their context is done and for any transaction while NestedTransaction is running on it.
+func LookupTx(tx *sql.Tx) (*Tx, bool) {
+	activeTxMu.Lock()
+	defer activeTxMu.Unlock()
+
+	handle := activeTx[tx].Value()
+	return handle, handle != nil
+}
+
+// Connection returns the name of the connection the transaction was started on
+func (t *Tx) Connection() string {
+	return t.connection
+}
+
+// getManager returns the manager the transaction was started from, the default manager for transactions not started by xqb
+func (t *Tx) getManager() *DBM {
+	if t.manager != nil {
+		return t.manager
+	}
+	return DBManager()
+}
+
+// AfterCommit registers a callback executed after the transaction commits.
+// Inside a savepoint (NestedTransaction) the callback waits for the outermost commit
+// and it's discarded when the savepoint rolls back. It runs immediately when the transaction already committed.
+func (t *Tx) AfterCommit(fn func()) {
+	t.mu.Lock()
+	if t.done {
+		outcome := t.outcome
+		t.mu.Unlock()
+		if outcome == txCommitted {
+			safeCall(fn)
+		}
+		return
+	}
+	scope := t.scopes[len(t.scopes)-1]
+	scope.afterCommit = append(scope.afterCommit, fn)
+	t.mu.Unlock()
+}
+
+// AfterRollback registers a callback executed after the transaction rolls back.
+// Inside a savepoint (NestedTransaction) the callback runs when the savepoint rolls back
+// or when the outermost transaction rolls back after the savepoint was released.
+// It runs immediately when the transaction already rolled back.
+func (t *Tx) AfterRollback(fn func()) {
+	t.mu.Lock()
+	if t.done {
+		outcome := t.outcome
+		t.mu.Unlock()
+		if outcome == txRolledBack {
+			safeCall(fn)
+		}
+		return
+	}
+	scope := t.scopes[len(t.scopes)-1]
+	scope.afterRollback = append(scope.afterRollback, fn)
+	t.mu.Unlock()
+}
+
+// Commit commits the transaction then runs the after commit callbacks,
+// the after rollback callbacks run instead when the commit fails.
+func (t *Tx) Commit() error {
+	err := t.Tx.Commit()
+	switch {
+	case err == nil:
+		t.finish(txCommitted)
+	case errors.Is(err, sql.ErrTxDone):
+		t.finishDone()
+	default:
+		err = t.getManager().translateError(t.connection, err)
+		if t.span != nil {
+			t.span.RecordError(err)
+		}
+		// a failed commit leaves nothing committed
+		t.finish(txRolledBack)
+	}
+	return err
+}
+
+// Rollback rolls the transaction back then runs the after rollback callbacks
+func (t *Tx) Rollback() error {
+	err := t.Tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		t.finishDone()
+		return err
+	}
+	t.finish(txRolledBack)
+	return err
+}
+
+// finishDone finishes a transaction the *sql.Tx reports already done: rolled back by database/sql when its
+// context is done, otherwise finished through the embedded *sql.Tx with an unknown outcome
+func (t *Tx) finishDone() {
+	if t.ctx != nil && t.ctx.Err() != nil {
+		t.finish(txRolledBack)
+		return
+	}
+	t.finish(txFinishedOutside)
+}
+
+// finish runs the callbacks of the outcome once and forgets the transaction
+func (t *Tx) finish(outcome txOutcome) {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return
+	}
+	t.done = true
+	t.outcome = outcome
+
+	var callbacks []func()
+	for _, scope := range t.scopes {
+		switch outcome {
+		case txCommitted:
+			callbacks = append(callbacks, scope.afterCommit...)
+		case txRolledBack:
+			callbacks = append(callbacks, scope.afterRollback...)
+		}
+	}
+	t.scopes = []*txScope{{}}
+	t.mu.Unlock()
+
+	if t.owned {
+		if t.untrack != nil {
+			t.untrack()
+		}
+		t.forget()
+	}
+
+	if t.stop != nil {
+		t.stop()
+	}
+
+	if t.span != nil {
+		if outcome != txFinishedOutside {
+			t.span.SetAttributes(map[string]any{"xqb.transaction.committed": outcome == txCommitted})
+		}
+		t.span.End()
+	}
+
+	for _, callback := range callbacks {
+		safeCall(callback)
+	}
+}
+
+// enterSavepoint opens a callbacks scope on the transaction handle and returns the savepoint name (sp_n)
+// transactions not started by xqb are registered while they have savepoints
+func enterSavepoint(tx *sql.Tx) (*Tx, string) {
+	handle, ok := LookupTx(tx)
+	if !ok {
+		handle = &Tx{Tx: tx, scopes: []*txScope{{}}}
+		registerTx(handle)
+	}
+
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+	handle.scopes = append(handle.scopes, &txScope{})
+
+	return handle, fmt.Sprintf("sp_%d", len(handle.scopes)-1)
+}
+
+// leaveSavepoint closes the innermost callbacks scope, a released savepoint hands its callbacks to the
+// enclosing scope while a rolled back one discards its after commit callbacks and runs its after rollback callbacks.
+// The callbacks of a savepoint that failed to open are discarded.
+func (t *Tx) leaveSavepoint(opened bool, released bool) {
+	t.mu.Lock()
+	if len(t.scopes) < 2 {
+		t.mu.Unlock()
+		return
+	}
+
+	scope := t.scopes[len(t.scopes)-1]
+	t.scopes = t.scopes[:len(t.scopes)-1]
+
+	var callbacks []func()
+	if released {
+		parent := t.scopes[len(t.scopes)-1]
+		parent.afterCommit = append(parent.afterCommit, scope.afterCommit...)
+		parent.afterRollback = append(parent.afterRollback, scope.afterRollback...)
+	} else if opened {
+		callbacks = scope.afterRollback
+	}
+	outermost := len(t.scopes) == 1
+	t.mu.Unlock()
+
+	if outermost && !t.owned {
+		t.forget()
+	}
+
+	for _, callback := range callbacks {
+		safeCall(callback)
+	}
+}
@@ -0,0 +1,169 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Tx_AfterCommitRunsOnCommit(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	tx, err := xqb.BeginTxHandleOn(fdb.name)
+	assert.NoError(t, err)
+
+	var events []string
+	tx.AfterCommit(func() { events = append(events, "committed") })
+	tx.AfterRollback(func() { events = append(events, "rolled back") })
+
+	_, err = xqb.Table("users").Connection(fdb.name).WithTx(tx.Tx).Where("id", "=", 1).Delete()
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"committed"}, events)
+
+	_, found := xqb.LookupTx(tx.Tx)
+	assert.False(t, found)
+
+	// registering on a committed transaction runs the callback immediately
+	tx.AfterCommit(func() { events = append(events, "late") })
+	assert.Equal(t, []string{"committed", "late"}, events)
+}
+
+func Test_BeginTxOn_ReturnsSqlTx(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	tx, err := xqb.BeginTxOn(fdb.name)
+	assert.NoError(t, err)
+
+	_, err = xqb.Table("users").Connection(fdb.name).WithTx(tx).Where("id", "=", 1).Delete()
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "COMMIT"}, fdb.Statements())
+}
+
+func Test_Tx_FinishedThroughSqlTxDiscardsCallbacks(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	tx, err := xqb.BeginTxHandleOn(fdb.name)
+	assert.NoError(t, err)
+
+	var events []string
+	tx.AfterCommit(func() { events = append(events, "committed") })
+	tx.AfterRollback(func() { events = append(events, "rolled back") })
+
+	// committed through the embedded *sql.Tx, the handle can't tell the outcome
+	assert.NoError(t, tx.Tx.Commit())
+	assert.ErrorIs(t, tx.Commit(), sql.ErrTxDone)
+	assert.ErrorIs(t, tx.Rollback(), sql.ErrTxDone)
+	assert.Empty(t, events)
+
+	_, found := xqb.LookupTx(tx.Tx)
+	assert.False(t, found)
+}
+
+func Test_Tx_ForgottenWhenContextIsDone(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tx, err := xqb.BeginTxCtxOn(ctx, fdb.name, nil)
+	assert.NoError(t, err)
+
+	var events []string
+	tx.AfterRollback(func() { events = append(events, "rolled back") })
+
+	_, found := xqb.LookupTx(tx.Tx)
+	assert.True(t, found)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, found := xqb.LookupTx(tx.Tx)
+		return !found
+	}, time.Second, time.Millisecond)
+
+	// database/sql rolls the transaction back when its context is done
+	assert.Error(t, tx.Commit())
+	assert.Equal(t, []string{"rolled back"}, events)
+}
+
+func Test_Tx_AfterRollbackRunsOnFailure(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	failure := errors.New("failure")
+
+	var events []string
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, _ *sql.Tx) error {
+		tx, ok := xqb.TxFromContext(ctx)
+		assert.True(t, ok)
+		tx.AfterCommit(func() { events = append(events, "committed") })
+		tx.AfterRollback(func() { events = append(events, "rolled back") })
+		return failure
+	})
+
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"rolled back"}, events)
+}
+
+func Test_Tx_SavepointCallbacksWaitForOutermostCommit(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, nil)
+
+	var events []string
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		handle, ok := xqb.LookupTx(tx)
+		assert.True(t, ok)
+
+		assert.NoError(t, xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+			handle.AfterCommit(func() { events = append(events, "released savepoint") })
+			return nil
+		}))
+		assert.Empty(t, events)
+
+		assert.Error(t, xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+			handle.AfterCommit(func() { events = append(events, "rolled back savepoint") })
+			handle.AfterRollback(func() { events = append(events, "savepoint rollback") })
+			return errors.New("failure")
+		}))
+		assert.Equal(t, []string{"savepoint rollback"}, events)
+
+		handle.AfterCommit(func() { events = append(events, "outer") })
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"savepoint rollback", "released savepoint", "outer"}, events)
+}
+
+func Test_Tx_ReleasedSavepointDiscardedWithParentSavepoint(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	var events []string
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		handle, _ := xqb.LookupTx(tx)
+
+		_ = xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+			assert.NoError(t, xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+				handle.AfterCommit(func() { events = append(events, "inner") })
+				return nil
+			}))
+			return errors.New("failure")
+		})
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SAVEPOINT sp_1",
+		"SAVEPOINT sp_2",
+		"RELEASE SAVEPOINT sp_2",
+		"ROLLBACK TO SAVEPOINT sp_1",
+		"COMMIT",
+	}, fdb.Statements())
+}
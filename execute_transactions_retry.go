@@ -0,0 +1,123 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/iMohamedSheta/xqb/dialects"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+)
+
+// RetryPolicy configures how transactions failed with a deadlock or a serialization failure are retried.
+// Zero fields fall back to the values of DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first one
+	BaseDelay   time.Duration // delay before the first retry, doubled on every retry
+	MaxDelay    time.Duration // upper bound of the delay between attempts
+}
+
+// DefaultRetryPolicy returns the retry policy used for zero RetryPolicy fields (3 attempts, 50ms doubled up to 2s)
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaults.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaults.MaxDelay
+	}
+	return p
+}
+
+// backoff returns the delay before the given retry (starting from 1), it grows exponentially
+// and half of it is randomized (jitter) so concurrent transactions don't retry in lockstep
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.MaxDelay
+	if shift := retry - 1; shift < 32 {
+		if exp := p.BaseDelay << shift; exp > 0 && exp < p.MaxDelay {
+			delay = exp
+		}
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// TransactionWithRetry runs a function inside a transaction on the given connection and runs it again
+// in a new transaction when it fails with an error the connection dialect classifies as retryable
+// (MySql deadlock 1213, Postgres serialization failure 40001 and deadlock 40P01).
+// The function may run several times so it must not have side effects outside the transaction.
+// The final error is wrapped in ErrTransactionFailed with the number of attempts.
+func TransactionWithRetry(connection string, policy RetryPolicy, fn func(*sql.Tx) error) error {
+	return DBManager().TransactionWithRetry(connection, policy, fn)
+}
+
+// TransactionWithRetry runs a function inside a retried transaction on the given connection of the manager (see TransactionWithRetry).
+func (m *DBM) TransactionWithRetry(connection string, policy RetryPolicy, fn func(*sql.Tx) error) error {
+	return m.TransactionWithRetryCtx(context.Background(), connection, policy, nil, func(_ context.Context, tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
+
+// TransactionWithRetryCtx is TransactionWithRetry with a context and transaction options (see TransactionCtxOn).
+// The wait between attempts stops when the context is done. When the context already carries a transaction
+// of the connection the function runs once in a savepoint, retrying belongs to the outermost transaction.
+func TransactionWithRetryCtx(ctx context.Context, connection string, policy RetryPolicy, opts *TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return DBManager().TransactionWithRetryCtx(ctx, connection, policy, opts, fn)
+}
+
+// TransactionWithRetryCtx is TransactionWithRetryCtx on the given connection of the manager.
+func (m *DBM) TransactionWithRetryCtx(ctx context.Context, connection string, policy RetryPolicy, opts *TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, ok := txHandleFromContext(ctx, m, connection); ok {
+		return m.TransactionCtxOn(ctx, connection, opts, fn)
+	}
+
+	conn, err := m.Connection(connection)
+	if err != nil || conn.DB == nil {
+		return fmt.Errorf("%w: invalid connection %s", xqbErr.ErrNoConnection, connection)
+	}
+	dialect := dialects.GetDialect(conn.Dialect.MappedDialect())
+
+	policy = policy.withDefaults()
+
+	attempt := 1
+	for {
+		err = m.TransactionCtxOn(ctx, connection, opts, fn)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= policy.MaxAttempts || !dialect.IsRetryableError(err) || ctx.Err() != nil {
+			break
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: transaction failed after %d attempt(s): %w", xqbErr.ErrTransactionFailed, attempt, errors.Join(err, ctx.Err()))
+		}
+		attempt++
+	}
+
+	return fmt.Errorf("%w: transaction failed after %d attempt(s): %w", xqbErr.ErrTransactionFailed, attempt, err)
+}
@@ -0,0 +1,123 @@
+package xqb_test
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// mysqlError mimics *mysql.MySQLError of go-sql-driver/mysql, exposing its fields with the xqb.NumberedError
+// and SQLState() methods since it isn't the driver type
+type mysqlError struct {
+	Number  uint16
+	State   [5]byte
+	Message string
+}
+
+func (e *mysqlError) Error() string {
+	return fmt.Sprintf("Error %d (%s): %s", e.Number, e.State[:], e.Message)
+}
+
+func (e *mysqlError) ErrorNumber() int {
+	return int(e.Number)
+}
+
+func (e *mysqlError) SQLState() string {
+	if e.State[0] == 0 {
+		return ""
+	}
+	return string(e.State[:])
+}
+
+// pgError mimics *pq.Error of lib/pq
+type pgError struct {
+	Code    string
+	Message string
+}
+
+func (e *pgError) Error() string {
+	return "pq: " + e.Message
+}
+
+func (e *pgError) SQLState() string {
+	return e.Code
+}
+
+var fastRetry = xqb.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Microsecond, MaxDelay: time.Millisecond}
+
+// failingDeletes fails the first n DELETE statements with the given error
+func failingDeletes(n int32, failure error) fakeHandler {
+	var calls atomic.Int32
+	return func(query string, args []any) (*fakeResult, error) {
+		if strings.HasPrefix(query, "DELETE") && calls.Add(1) <= n {
+			return nil, failure
+		}
+		return &fakeResult{rowsAffected: 1}, nil
+	}
+}
+
+func deleteUser(name string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := xqb.Table("users").Connection(name).WithTx(tx).Where("id", "=", 1).Delete()
+		return err
+	}
+}
+
+func Test_TransactionWithRetry_RetriesDeadlock(t *testing.T) {
+	deadlock := &mysqlError{Number: 1213, State: [5]byte{'4', '0', '0', '0', '1'}, Message: "Deadlock found when trying to get lock"}
+	fdb := newFakeConnection(t, xqb.DialectMySql, failingDeletes(2, deadlock))
+
+	err := xqb.TransactionWithRetry(fdb.name, fastRetry, deleteUser(fdb.name))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "ROLLBACK",
+		"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "ROLLBACK",
+		"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "COMMIT",
+	}, fdb.Statements())
+}
+
+func Test_TransactionWithRetry_RetriesPostgresSerializationFailure(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, failingDeletes(1, &pgError{Code: "40001", Message: "could not serialize access"}))
+
+	attempts := 0
+	err := xqb.TransactionWithRetry(fdb.name, fastRetry, func(tx *sql.Tx) error {
+		attempts++
+		_, err := xqb.Sql("DELETE FROM users").Connection(fdb.name).WithTx(tx).Execute()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func Test_TransactionWithRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	deadlock := &pgError{Code: "40P01", Message: "deadlock detected"}
+	fdb := newFakeConnection(t, xqb.DialectPostgres, failingDeletes(10, deadlock))
+
+	err := xqb.TransactionWithRetry(fdb.name, fastRetry, deleteUser(fdb.name))
+
+	assert.ErrorIs(t, err, xqbErr.ErrTransactionFailed)
+	assert.ErrorIs(t, err, deadlock)
+	assert.Contains(t, err.Error(), "after 3 attempt(s)")
+	assert.Equal(t, 3, strings.Count(strings.Join(fdb.Statements(), "\n"), "BEGIN"))
+}
+
+func Test_TransactionWithRetry_DoesNotRetryOtherErrors(t *testing.T) {
+	duplicate := &mysqlError{Number: 1062, State: [5]byte{'2', '3', '0', '0', '0'}, Message: "Duplicate entry"}
+	fdb := newFakeConnection(t, xqb.DialectMySql, failingDeletes(10, duplicate))
+
+	err := xqb.TransactionWithRetry(fdb.name, fastRetry, deleteUser(fdb.name))
+
+	assert.ErrorIs(t, err, xqbErr.ErrTransactionFailed)
+	assert.ErrorIs(t, err, duplicate)
+	assert.Contains(t, err.Error(), "after 1 attempt(s)")
+	assert.Equal(t, []string{"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "ROLLBACK"}, fdb.Statements())
+}
@@ -0,0 +1,251 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/iMohamedSheta/xqb/shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TransactionOn_Commit(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		_, err := xqb.Table("users").Connection(fdb.name).WithTx(tx).Where("id", "=", 1).Delete()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "COMMIT"}, fdb.Statements())
+}
+
+func Test_NestedTransaction_ReleasesSavepoints(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		return xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+			return xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+				return nil
+			})
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SAVEPOINT sp_1",
+		"SAVEPOINT sp_2",
+		"RELEASE SAVEPOINT sp_2",
+		"RELEASE SAVEPOINT sp_1",
+		"COMMIT",
+	}, fdb.Statements())
+}
+
+func Test_NestedTransaction_RollsBackToSavepoint(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, nil)
+	failure := errors.New("inner failure")
+
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		innerErr := xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+			return failure
+		})
+		assert.ErrorIs(t, innerErr, failure)
+
+		// the outer transaction is still usable and the savepoint name is reused
+		return xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+			return nil
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SAVEPOINT sp_1",
+		"ROLLBACK TO SAVEPOINT sp_1",
+		"SAVEPOINT sp_1",
+		"RELEASE SAVEPOINT sp_1",
+		"COMMIT",
+	}, fdb.Statements())
+}
+
+func Test_TransactionOn_DoesNotJoinRunningTransaction(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		// without the context the transaction isn't known and a new one starts
+		assert.NoError(t, xqb.TransactionOn(fdb.name, func(other *sql.Tx) error {
+			assert.NotSame(t, tx, other)
+			return nil
+		}))
+
+		// an explicit opt-out starts an unrelated transaction
+		assert.NoError(t, xqb.TransactionCtxOn(xqb.ContextWithoutTx(ctx), fdb.name, nil, func(_ context.Context, other *sql.Tx) error {
+			assert.NotSame(t, tx, other)
+			return nil
+		}))
+
+		return xqb.TransactionCtxOn(ctx, fdb.name, nil, func(ctx context.Context, inner *sql.Tx) error {
+			assert.Same(t, tx, inner)
+			return nil
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"BEGIN",
+		"COMMIT",
+		"BEGIN",
+		"COMMIT",
+		"SAVEPOINT sp_1",
+		"RELEASE SAVEPOINT sp_1",
+		"COMMIT",
+	}, fdb.Statements())
+}
+
+func Test_NestedTransaction_NilUsesDefaultConnection(t *testing.T) {
+	fdb := &fakeDB{name: "nested_default"}
+	name := xqb.DBManager().GetDefaultConnectionName()
+	previous, err := xqb.GetConnection(name)
+	assert.NoError(t, err)
+	// the default connection is swapped for the fake then restored
+	assert.NoError(t, xqb.AddConnection(&xqb.Connection{Name: name, DB: sql.OpenDB(fdb), Dialect: xqb.DialectMySql}))
+	t.Cleanup(func() { _ = xqb.AddConnection(previous) })
+
+	err = xqb.NestedTransaction(nil, func(tx *sql.Tx) error {
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "COMMIT"}, fdb.Statements())
+}
+
+func Test_NestedTransaction_RecoversPanic(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		return xqb.NestedTransaction(tx, func(tx *sql.Tx) error {
+			panic("boom")
+		})
+	})
+
+	assert.ErrorIs(t, err, xqbErr.ErrTransactionFailed)
+	assert.Equal(t, []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK"}, fdb.Statements())
+}
+
+func Test_TransactionCtxOn_AppliesOptions(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, nil)
+
+	opts := &xqb.TxOptions{
+		Isolation:  sql.LevelSerializable,
+		ReadOnly:   true,
+		Deferrable: true,
+		Timeout:    2 * time.Second,
+	}
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, opts, func(ctx context.Context, tx *sql.Tx) error {
+		_, deadline := ctx.Deadline()
+		assert.True(t, deadline)
+
+		_, err := xqb.Table("users").Connection(fdb.name).SetDialect(types.DialectPostgres).WithContext(ctx).WithTx(tx).Get()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN READ ONLY ISOLATION LEVEL SERIALIZABLE",
+		"SET TRANSACTION DEFERRABLE",
+		"SET LOCAL statement_timeout = 2000",
+		`SELECT * FROM "users"`,
+		"COMMIT",
+	}, fdb.Statements())
+}
+
+func Test_TransactionCtxOn_UnsupportedOption(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, &xqb.TxOptions{Deferrable: true}, func(ctx context.Context, tx *sql.Tx) error {
+		t.Fatal("the function must not run")
+		return nil
+	})
+
+	assert.ErrorIs(t, err, xqbErr.ErrUnsupportedFeature)
+	assert.Empty(t, fdb.Statements())
+}
+
+func Test_TransactionCtxOn_RollsBackOnTimeout(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, &xqb.TxOptions{Timeout: 10 * time.Millisecond}, func(ctx context.Context, tx *sql.Tx) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	assert.ErrorIs(t, err, xqbErr.ErrTransactionFailed)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Eventually(t, func() bool {
+		return slices.Contains(fdb.Statements(), "ROLLBACK")
+	}, time.Second, time.Millisecond)
+	assert.NotContains(t, fdb.Statements(), "COMMIT")
+}
+
+func Test_TransactionCtxOn_RollsBackOnCancel(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := xqb.TransactionCtxOn(ctx, fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		cancel()
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Eventually(t, func() bool {
+		return slices.Contains(fdb.Statements(), "ROLLBACK")
+	}, time.Second, time.Millisecond)
+	assert.NotContains(t, fdb.Statements(), "COMMIT")
+}
+
+func Test_BeginTxCtxOn_TimeoutRollsBack(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	tx, err := xqb.BeginTxCtxOn(context.Background(), fdb.name, &xqb.TxOptions{Isolation: sql.LevelReadCommitted, Timeout: 10 * time.Millisecond})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return slices.Contains(fdb.Statements(), "ROLLBACK")
+	}, time.Second, time.Millisecond)
+	assert.ErrorIs(t, tx.Commit(), sql.ErrTxDone)
+	assert.Equal(t, "BEGIN ISOLATION LEVEL READ COMMITTED", fdb.Statements()[0])
+}
+
+// contextTracer keeps the contexts the spans are started with
+type contextTracer struct {
+	contexts []context.Context
+}
+
+func (c *contextTracer) StartSpan(ctx context.Context, name string) (context.Context, xqb.Span) {
+	c.contexts = append(c.contexts, ctx)
+	return ctx, nil
+}
+
+func Test_BeginTxCtxOn_CommitReleasesTheTimeout(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	tracer := &contextTracer{}
+	xqb.DefaultSettings().SetTracer(tracer)
+	t.Cleanup(func() { xqb.DefaultSettings().SetTracer(nil) })
+
+	tx, err := xqb.BeginTxCtxOn(context.Background(), fdb.name, &xqb.TxOptions{Timeout: time.Hour})
+	assert.NoError(t, err)
+	assert.Len(t, tracer.contexts, 1)
+	assert.NoError(t, tracer.contexts[0].Err())
+
+	assert.NoError(t, tx.Commit())
+	assert.ErrorIs(t, tracer.contexts[0].Err(), context.Canceled)
+}
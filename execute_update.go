@@ -26,12 +26,7 @@ func (qb *QueryBuilder) update(data map[string]any) (sql.Result, error) {
 		return nil, fmt.Errorf("%w: Update() Failed to build the sql, %v", xqbErr.ErrInvalidQuery, err)
 	}
 
-	return Sql(query, args...).
-		WithContext(qb.ctx).
-		WithAfterExec(qb.settings.GetOnAfterQueryExecution()).
-		Connection(qb.connection).
-		WithTx(qb.tx).
-		Execute()
+	return qb.sqlQuery(query, args).Execute()
 }
 
 // UpdateSql return sql query and bindings for Update()
@@ -0,0 +1,309 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+)
+
+// fakeResult is the response returned by the fake database for a statement
+type fakeResult struct {
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+	lastInsertId int64
+}
+
+// fakeHandler answers a statement executed against the fake database
+type fakeHandler func(query string, args []any) (*fakeResult, error)
+
+// fakeDB is a minimal database/sql driver used to test query execution without a real database.
+// It records every statement (including BEGIN/COMMIT/ROLLBACK) and tracks the opened rows.
+type fakeDB struct {
+	mu       sync.Mutex
+	name     string
+	handler  fakeHandler
+	log      []string
+	txLog    []string
+	openRows int
+	pingErr  error
+	prepared []string
+	closed   int
+}
+
+// newFakeConnection registers a fake connection with the given dialect for the duration of the test
+func newFakeConnection(t *testing.T, dialect xqb.Dialect, handler fakeHandler) *fakeDB {
+	t.Helper()
+
+	fdb := &fakeDB{
+		name:    "fake_" + strings.ReplaceAll(t.Name(), "/", "_"),
+		handler: handler,
+	}
+	for i := 2; xqb.HasConnection(fdb.name); i++ {
+		fdb.name = fmt.Sprintf("fake_%s_%d", strings.ReplaceAll(t.Name(), "/", "_"), i)
+	}
+
+	if err := xqb.AddConnection(&xqb.Connection{
+		Name:    fdb.name,
+		DB:      sql.OpenDB(fdb),
+		Dialect: dialect,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = xqb.Close(fdb.name)
+	})
+
+	return fdb
+}
+
+// Statements returns the statements executed against the fake database in order
+func (f *fakeDB) Statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.log...)
+}
+
+// TxStatements returns the statements executed inside a transaction in order
+func (f *fakeDB) TxStatements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.txLog...)
+}
+
+// OpenRows returns the number of rows that are not closed yet
+func (f *fakeDB) OpenRows() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.openRows
+}
+
+// Prepared returns the statements prepared on the fake database in order
+func (f *fakeDB) Prepared() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prepared...)
+}
+
+// ClosedStatements returns the number of prepared statements closed
+func (f *fakeDB) ClosedStatements() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// SetPingError makes the pings of the fake database fail with err, nil makes them succeed
+func (f *fakeDB) SetPingError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pingErr = err
+}
+
+func (f *fakeDB) run(query string, args []driver.NamedValue, inTx bool) (*fakeResult, error) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	f.mu.Lock()
+	f.log = append(f.log, query)
+	if inTx {
+		f.txLog = append(f.txLog, query)
+	}
+	f.mu.Unlock()
+
+	if f.handler == nil {
+		return &fakeResult{}, nil
+	}
+
+	res, err := f.handler(query, values)
+	if res == nil {
+		res = &fakeResult{}
+	}
+	return res, err
+}
+
+func (f *fakeDB) record(statement string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, statement)
+}
+
+// Connect implements driver.Connector
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+// Driver implements driver.Connector
+func (f *fakeDB) Driver() driver.Driver {
+	return fakeDriver{db: f}
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct {
+	db   *fakeDB
+	inTx bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	c.db.prepared = append(c.db.prepared, query)
+	c.db.mu.Unlock()
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+// Ping implements driver.Pinger
+func (c *fakeConn) Ping(context.Context) error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return c.db.pingErr
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	statement := "BEGIN"
+	if opts.ReadOnly {
+		statement += " READ ONLY"
+	}
+	if level := sql.IsolationLevel(opts.Isolation); level != sql.LevelDefault {
+		statement += " ISOLATION LEVEL " + strings.ToUpper(level.String())
+	}
+	c.db.record(statement)
+	c.inTx = true
+	return &fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.db.run(query, args, c.inTx)
+	if err != nil {
+		return nil, err
+	}
+	return driverResult{res: res}, nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.db.run(query, args, c.inTx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.db.mu.Lock()
+	c.db.openRows++
+	c.db.mu.Unlock()
+
+	return &fakeRows{db: c.db, res: res}, nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	s.conn.db.mu.Lock()
+	s.conn.db.closed++
+	s.conn.db.mu.Unlock()
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, toNamedValues(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, toNamedValues(args))
+}
+
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.conn.inTx = false
+	tx.conn.db.record("COMMIT")
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.inTx = false
+	tx.conn.db.record("ROLLBACK")
+	return nil
+}
+
+type driverResult struct {
+	res *fakeResult
+}
+
+func (r driverResult) LastInsertId() (int64, error) {
+	return r.res.lastInsertId, nil
+}
+
+func (r driverResult) RowsAffected() (int64, error) {
+	return r.res.rowsAffected, nil
+}
+
+type fakeRows struct {
+	db     *fakeDB
+	res    *fakeResult
+	pos    int
+	closed bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.res.columns
+}
+
+func (r *fakeRows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	r.db.mu.Lock()
+	r.db.openRows--
+	r.db.mu.Unlock()
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.res.rows) {
+		return io.EOF
+	}
+	copy(dest, r.res.rows[r.pos])
+	r.pos++
+	return nil
+}
@@ -0,0 +1,97 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// QueryKind is the SqlQuery method executing a statement
+type QueryKind string
+
+const (
+	QueryKindExecute  QueryKind = "execute"
+	QueryKindQuery    QueryKind = "query"
+	QueryKindQueryRow QueryKind = "query_row"
+)
+
+// QueryEvent describes a statement going through the interceptor chain.
+// Interceptors may rewrite Sql and Bindings, or change Connection and Tx, before calling next.
+type QueryEvent struct {
+	Kind       QueryKind
+	Sql        string
+	Bindings   []any
+	Connection string
+	Tx         *sql.Tx // nil when the statement runs outside a transaction
+	Replica    bool    // the statement may run on a read replica of the connection
+
+	manager *DBM
+	db      *sql.DB // the database that ran the statement outside a transaction
+}
+
+// getManager returns the manager of the connection, the default manager for events created by interceptors
+func (e *QueryEvent) getManager() *DBM {
+	if e.manager != nil {
+		return e.manager
+	}
+	return DBManager()
+}
+
+// Result is the outcome of an intercepted statement, Exec is set for Execute and Rows for Query/QueryRow.
+// Interceptors can short-circuit the execution with their own result, see BufferedRows.Result for cached rows.
+type Result struct {
+	Exec sql.Result
+	Rows *sql.Rows
+}
+
+// QueryHandler executes a statement, it's the next step given to an interceptor
+type QueryHandler func(ctx context.Context, event *QueryEvent) (Result, error)
+
+// Interceptor wraps the execution of SqlQuery.Execute/Query/QueryRow, it must call next to run the
+// remaining interceptors and the database round trip or return its own result to skip them.
+//
+// Example:
+//
+//	xqb.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+//		start := time.Now()
+//		result, err := next(ctx, event)
+//		log.Printf("%s took %s err=%v", event.Sql, time.Since(start), err)
+//		return result, err
+//	})
+type Interceptor func(ctx context.Context, event *QueryEvent, next QueryHandler) (Result, error)
+
+// Use appends interceptors to the default settings (see QueryBuilderSettings.Use)
+func Use(interceptors ...Interceptor) {
+	DefaultSettings().Use(interceptors...)
+}
+
+// Use appends interceptors to the chain, the first one registered is the outermost
+func (s *QueryBuilderSettings) Use(interceptors ...Interceptor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interceptors = append(s.interceptors, interceptors...)
+}
+
+// GetInterceptors returns the interceptors in the order they were registered
+func (s *QueryBuilderSettings) GetInterceptors() []Interceptor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Interceptor(nil), s.interceptors...)
+}
+
+// ClearInterceptors removes all the interceptors
+func (s *QueryBuilderSettings) ClearInterceptors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interceptors = nil
+}
+
+// chainInterceptors wraps the handler with the interceptors, the first one being the outermost
+func chainInterceptors(interceptors []Interceptor, handler QueryHandler) QueryHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, event *QueryEvent) (Result, error) {
+			return interceptor(ctx, event, next)
+		}
+	}
+	return handler
+}
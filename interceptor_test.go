@@ -0,0 +1,135 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Interceptors_WrapExecutionInOrder(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+
+	var calls []string
+	trace := func(name string) xqb.Interceptor {
+		return func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+			calls = append(calls, name+" before "+string(event.Kind))
+			result, err := next(ctx, event)
+			calls = append(calls, name+" after")
+			return result, err
+		}
+	}
+
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(trace("first"), trace("second"))
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Get()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first before query", "second before query", "second after", "first after"}, calls)
+}
+
+func Test_Interceptors_RewriteSql(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		event.Sql = "/* app:users */ " + event.Sql
+		return next(ctx, event)
+	})
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("id", "=", 1).Delete()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/* app:users */ DELETE FROM `users` WHERE `id` = ?"}, fdb.Statements())
+}
+
+func Test_Interceptors_ShortCircuitWithBufferedRows(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(2), nil
+	})
+
+	cache := map[string]*xqb.BufferedRows{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		if event.Kind == xqb.QueryKindExecute {
+			return next(ctx, event)
+		}
+		if buffered, ok := cache[event.Sql]; ok {
+			return buffered.Result()
+		}
+
+		result, err := next(ctx, event)
+		if err != nil {
+			return result, err
+		}
+		buffered, err := xqb.BufferRows(result.Rows)
+		if err != nil {
+			return xqb.Result{}, err
+		}
+		cache[event.Sql] = buffered
+		return buffered.Result()
+	})
+
+	for range 3 {
+		users, err := xqb.Model[User]().Connection(fdb.name).WithSettings(settings).Get()
+		assert.NoError(t, err)
+		assert.Len(t, users, 2)
+		assert.Equal(t, "user", users[1].Name)
+	}
+
+	var id int64
+	assert.NoError(t, xqb.Sql("SELECT * FROM `users`").Connection(fdb.name).WithSettings(settings).QueryRow(&id, new(string)))
+	assert.Equal(t, int64(1), id)
+
+	assert.Equal(t, []string{"SELECT * FROM `users`"}, fdb.Statements())
+	assert.Equal(t, 0, fdb.OpenRows())
+}
+
+func Test_Interceptors_SeeTranslatedErrors(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return nil, &mysqlError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}
+	})
+
+	var seen error
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		result, err := next(ctx, event)
+		seen = err
+		return result, err
+	})
+
+	err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Insert([]map[string]any{{"id": 1}})
+
+	assert.ErrorIs(t, err, xqb.ErrUniqueViolation)
+	assert.ErrorIs(t, seen, xqb.ErrUniqueViolation)
+}
+
+func Test_Interceptors_GlobalUseAndEmptyResult(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	t.Cleanup(xqb.DefaultSettings().ClearInterceptors)
+
+	var events []xqb.QueryEvent
+	xqb.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		events = append(events, *event)
+		return xqb.Result{}, nil
+	})
+
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		_, err := xqb.Sql("UPDATE users SET name = ?", "john").Connection(fdb.name).WithTx(tx).Execute()
+		return err
+	})
+
+	assert.ErrorIs(t, err, xqbErr.ErrInvalidResult)
+	assert.Len(t, events, 1)
+	assert.Equal(t, xqb.QueryKindExecute, events[0].Kind)
+	assert.Equal(t, []any{"john"}, events[0].Bindings)
+	assert.Equal(t, fdb.name, events[0].Connection)
+	assert.NotNil(t, events[0].Tx)
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, fdb.Statements())
+}
@@ -1,5 +1,7 @@
 package xqb
 
+import "github.com/iMohamedSheta/xqb/shared/types"
+
 // Limit adds a LIMIT clause
 func (qb *QueryBuilder) Limit(limit int) *QueryBuilder {
 	qb.limit = limit
@@ -26,3 +28,50 @@ func (qb *QueryBuilder) Take(limit int) *QueryBuilder {
 func (qb *QueryBuilder) ForPage(page int, perPage int) *QueryBuilder {
 	return qb.Skip((page - 1) * perPage).Take(perPage)
 }
+
+// ForPageAfterId limits the query to the next page of results after the given id (keyset pagination)
+// Any existing ORDER BY is replaced with the key column ordered ascending
+func (qb *QueryBuilder) ForPageAfterId(perPage int, lastId any, column string) *QueryBuilder {
+	return qb.forPageByKey(perPage, lastId, column, ">", "ASC")
+}
+
+// ForPageBeforeId limits the query to the next page of results before the given id (keyset pagination)
+// Any existing ORDER BY is replaced with the key column ordered descending
+func (qb *QueryBuilder) ForPageBeforeId(perPage int, lastId any, column string) *QueryBuilder {
+	return qb.forPageByKey(perPage, lastId, column, "<", "DESC")
+}
+
+// forPageByKey applies the keyset condition, ordering and limit for ForPageAfterId/ForPageBeforeId
+func (qb *QueryBuilder) forPageByKey(perPage int, lastId any, column string, operator string, direction string) *QueryBuilder {
+	if column == "" {
+		column = "id"
+	}
+
+	if lastId != nil {
+		qb.groupOrWheres()
+		qb.Where(column, operator, lastId)
+	}
+
+	qb.orderBy = nil
+	qb.offset = 0
+
+	return qb.OrderBy(column, direction).Limit(perPage)
+}
+
+// groupOrWheres wraps the current WHERE conditions in a group when they contain an OR connector
+// so conditions appended afterwards with AND apply to the whole existing clause
+func (qb *QueryBuilder) groupOrWheres() {
+	if len(qb.where) < 2 {
+		return
+	}
+
+	for _, condition := range qb.where[1:] {
+		if condition.Connector == types.OR {
+			qb.where = []*types.WhereCondition{{
+				Group:     qb.where,
+				Connector: types.AND,
+			}}
+			return
+		}
+	}
+}
@@ -191,3 +191,60 @@ func TestForPageWithGroupByHaving(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestForPageAfterId(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, dialect types.Dialect) {
+		qb := xqb.Table("users").SetDialect(dialect).
+			Where("status", "=", "active").
+			OrderBy("name", "ASC").
+			ForPageAfterId(15, 30, "id")
+
+		sql, bindings, err := qb.ToSql()
+
+		expectedSql := map[types.Dialect]string{
+			types.DialectMySql:    "SELECT * FROM `users` WHERE `status` = ? AND `id` > ? ORDER BY `id` ASC LIMIT 15",
+			types.DialectPostgres: `SELECT * FROM "users" WHERE "status" = $1 AND "id" > $2 ORDER BY "id" ASC LIMIT 15`,
+		}
+
+		assert.Equal(t, expectedSql[dialect], sql)
+		assert.Equal(t, []any{"active", 30}, bindings)
+		assert.NoError(t, err)
+	})
+}
+
+func TestForPageBeforeIdGroupsOrWheres(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, dialect types.Dialect) {
+		qb := xqb.Table("users").SetDialect(dialect).
+			Where("role", "=", "admin").
+			OrWhere("role", "=", "owner").
+			ForPageBeforeId(10, 100, "users.id")
+
+		sql, bindings, err := qb.ToSql()
+
+		expectedSql := map[types.Dialect]string{
+			types.DialectMySql:    "SELECT * FROM `users` WHERE (`role` = ? OR `role` = ?) AND `users`.`id` < ? ORDER BY `users`.`id` DESC LIMIT 10",
+			types.DialectPostgres: `SELECT * FROM "users" WHERE ("role" = $1 OR "role" = $2) AND "users"."id" < $3 ORDER BY "users"."id" DESC LIMIT 10`,
+		}
+
+		assert.Equal(t, expectedSql[dialect], sql)
+		assert.Equal(t, []any{"admin", "owner", 100}, bindings)
+		assert.NoError(t, err)
+	})
+}
+
+func TestForPageAfterIdFirstPage(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, dialect types.Dialect) {
+		qb := xqb.Table("users").SetDialect(dialect).Offset(20).ForPageAfterId(5, nil, "")
+
+		sql, bindings, err := qb.ToSql()
+
+		expectedSql := map[types.Dialect]string{
+			types.DialectMySql:    "SELECT * FROM `users` ORDER BY `id` ASC LIMIT 5",
+			types.DialectPostgres: `SELECT * FROM "users" ORDER BY "id" ASC LIMIT 5`,
+		}
+
+		assert.Equal(t, expectedSql[dialect], sql)
+		assert.Empty(t, bindings)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,47 @@
+package xqb
+
+// SetSettings sets the settings used by the builders, raw queries and transactions of the manager,
+// nil restores the default settings
+func (m *DBM) SetSettings(settings *QueryBuilderSettings) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.settings = settings
+}
+
+// GetSettings returns the settings of the manager, DefaultSettings unless SetSettings was called
+func (m *DBM) GetSettings() *QueryBuilderSettings {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.settings != nil {
+		return m.settings
+	}
+	return DefaultSettings()
+}
+
+// Query creates a new QueryBuilder on the default connection of the manager
+func (m *DBM) Query() *QueryBuilder {
+	return newQueryBuilder(m)
+}
+
+// Table creates a new QueryBuilder for a specific table on the default connection of the manager
+func (m *DBM) Table(table string) *QueryBuilder {
+	return m.Query().Table(table)
+}
+
+// Sql creates a raw sql query on the default connection of the manager
+func (m *DBM) Sql(sql string, args ...any) *SqlQuery {
+	return &SqlQuery{
+		manager:    m,
+		connection: m.GetDefaultConnectionName(),
+		sql:        sql,
+		args:       args,
+	}
+}
+
+// ModelOn creates a ModelBuilder on the default connection of the manager
+func ModelOn[T ModelInterface](manager *DBM) *ModelBuilder[T] {
+	var model T
+	return &ModelBuilder[T]{
+		QueryBuilder: manager.Table(model.Table()),
+	}
+}
@@ -0,0 +1,113 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeManager creates a manager whose default connection is a fake database with the given dialect
+func newFakeManager(t *testing.T, dialect xqb.Dialect, handler fakeHandler) (*xqb.DBM, *fakeDB) {
+	t.Helper()
+
+	fdb := &fakeDB{name: "default", handler: handler}
+	mgr := xqb.NewManager()
+	if err := mgr.SetConnection(&xqb.Connection{Name: "default", DB: sql.OpenDB(fdb), Dialect: dialect}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = mgr.CloseAllConnections()
+	})
+
+	return mgr, fdb
+}
+
+func Test_Manager_IsolatedFromDefaultManager(t *testing.T) {
+	mysqlMgr, mysqlDB := newFakeManager(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(2), nil
+	})
+	pgMgr, pgDB := newFakeManager(t, xqb.DialectPostgres, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+
+	users, err := mysqlMgr.Table("users").Where("id", ">", 1).Get()
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	users, err = pgMgr.Table("users").Where("id", ">", 1).Get()
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	_, err = pgMgr.Sql("DELETE FROM users WHERE id = $1", 1).Execute()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"SELECT * FROM `users` WHERE `id` > ?"}, mysqlDB.Statements())
+	assert.Equal(t, []string{`SELECT * FROM "users" WHERE "id" > $1`, "DELETE FROM users WHERE id = $1"}, pgDB.Statements())
+	assert.False(t, xqb.DBManager().HasConnection("default"), "the default manager connection is untouched")
+}
+
+func Test_Manager_Model(t *testing.T) {
+	mgr, fdb := newFakeManager(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+
+	users, err := xqb.ModelOn[User](mgr).Get()
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, []string{"SELECT * FROM `users`"}, fdb.Statements())
+}
+
+func Test_Manager_TransactionPropagatesOnItsConnections(t *testing.T) {
+	mgr, fdb := newFakeManager(t, xqb.DialectMySql, nil)
+	other, otherDB := newFakeManager(t, xqb.DialectMySql, nil)
+
+	err := mgr.TransactionCtx(context.Background(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := mgr.Table("users").WithContext(ctx).Where("id", "=", 1).Delete(); err != nil {
+			return err
+		}
+		// same connection name on another manager: not part of the transaction
+		_, err := other.Table("posts").WithContext(ctx).Where("id", "=", 1).Delete()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "COMMIT"}, fdb.Statements())
+	assert.Equal(t, []string{"DELETE FROM `users` WHERE `id` = ?"}, fdb.TxStatements())
+	assert.Equal(t, []string{"DELETE FROM `posts` WHERE `id` = ?"}, otherDB.Statements())
+	assert.Empty(t, otherDB.TxStatements())
+}
+
+func Test_Manager_BeginTx(t *testing.T) {
+	mgr, fdb := newFakeManager(t, xqb.DialectMySql, nil)
+
+	tx, err := mgr.BeginTxHandle()
+	assert.NoError(t, err)
+	assert.Equal(t, "default", tx.Connection())
+
+	_, err = mgr.Table("users").WithTx(tx.Tx).Where("id", "=", 1).Delete()
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+
+	assert.Equal(t, []string{"BEGIN", "DELETE FROM `users` WHERE `id` = ?", "ROLLBACK"}, fdb.Statements())
+
+	err = mgr.Transaction(func(tx *sql.Tx) error {
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func Test_Manager_SqlFallsBackToItsDefaultConnection(t *testing.T) {
+	mgr, _ := newFakeManager(t, xqb.DialectMySql, nil)
+	primary := &fakeDB{name: "primary"}
+	assert.NoError(t, mgr.SetConnection(&xqb.Connection{Name: "primary", DB: sql.OpenDB(primary), Dialect: xqb.DialectMySql}))
+	assert.NoError(t, mgr.SetDefaultConnection("primary"))
+
+	_, err := mgr.Sql("DELETE FROM users").Connection("missing").Execute()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DELETE FROM users"}, primary.Statements())
+}
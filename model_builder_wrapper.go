@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"iter"
+	"time"
 
 	"github.com/iMohamedSheta/xqb/shared/types"
 )
@@ -48,6 +50,21 @@ func (mq *ModelBuilder[T]) WithTx(tx *sql.Tx) *ModelBuilder[T] {
 	return mq
 }
 
+func (mq *ModelBuilder[T]) WithoutTx() *ModelBuilder[T] {
+	mq.QueryBuilder.WithoutTx()
+	return mq
+}
+
+func (mq *ModelBuilder[T]) UsePrimary() *ModelBuilder[T] {
+	mq.QueryBuilder.UsePrimary()
+	return mq
+}
+
+func (mq *ModelBuilder[T]) Cache(ttl time.Duration, tags ...string) *ModelBuilder[T] {
+	mq.QueryBuilder.Cache(ttl, tags...)
+	return mq
+}
+
 func (mq *ModelBuilder[T]) WithSettings(settings *QueryBuilderSettings) *ModelBuilder[T] {
 	mq.QueryBuilder.WithSettings(settings)
 	return mq
@@ -463,6 +480,18 @@ func (qb *ModelBuilder[T]) ForPage(page int, perPage int) *ModelBuilder[T] {
 	return qb.Skip((page - 1) * perPage).Take(perPage)
 }
 
+// ForPageAfterId limits the query to the next page of results after the given id (keyset pagination)
+func (mq *ModelBuilder[T]) ForPageAfterId(perPage int, lastId any, column string) *ModelBuilder[T] {
+	mq.QueryBuilder.ForPageAfterId(perPage, lastId, column)
+	return mq
+}
+
+// ForPageBeforeId limits the query to the next page of results before the given id (keyset pagination)
+func (mq *ModelBuilder[T]) ForPageBeforeId(perPage int, lastId any, column string) *ModelBuilder[T] {
+	mq.QueryBuilder.ForPageBeforeId(perPage, lastId, column)
+	return mq
+}
+
 // ============================================================================
 // LOCK Methods
 // ============================================================================
@@ -615,16 +644,46 @@ func (mq *ModelBuilder[T]) FindOrFail(id any) (*T, error) {
 	return &model, nil
 }
 
-// Paginate returns paginated results with optional count metadata
-func (mq *ModelBuilder[T]) Paginate(perPage int, page int, countBy string) ([]T, map[string]any, error) {
-	data, meta, err := mq.QueryBuilder.Paginate(perPage, page, countBy)
+// Paginate returns a page of T with the total count of records counted by the given column
+func (mq *ModelBuilder[T]) Paginate(perPage int, page int, countBy string) (*Paginator[T], error) {
+	paginator, err := mq.QueryBuilder.Paginate(perPage, page, countBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []T
+	if err := Bind(paginator.Items, &results); err != nil {
+		return nil, fmt.Errorf("%w: Paginate() failed to bind results: %v", ErrInvalidResult, err)
+	}
+
+	return mapPaginator(paginator, results), nil
+}
+
+// SimplePaginate returns a page of T without counting the records
+func (mq *ModelBuilder[T]) SimplePaginate(perPage int, page int) (*Paginator[T], error) {
+	paginator, err := mq.QueryBuilder.SimplePaginate(perPage, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []T
+	if err := Bind(paginator.Items, &results); err != nil {
+		return nil, fmt.Errorf("%w: SimplePaginate() failed to bind results: %v", ErrInvalidResult, err)
+	}
+
+	return mapPaginator(paginator, results), nil
+}
+
+// CursorPaginate returns results of T paginated with keyset conditions derived from the OrderBy columns
+func (mq *ModelBuilder[T]) CursorPaginate(perPage int, cursor string) ([]T, *CursorPaginationMeta, error) {
+	data, meta, err := mq.QueryBuilder.CursorPaginate(perPage, cursor)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var results []T
 	if err := Bind(data, &results); err != nil {
-		return nil, nil, fmt.Errorf("%w: Paginate() failed to bind results: %v", ErrInvalidResult, err)
+		return nil, nil, fmt.Errorf("%w: CursorPaginate() failed to bind results: %v", ErrInvalidResult, err)
 	}
 
 	return results, meta, nil
@@ -641,6 +700,72 @@ func (mq *ModelBuilder[T]) Chunks(chunkSize int, closure func(results []T) error
 	})
 }
 
+// ChunkById processes results in batches of T using keyset pagination on the given column
+func (mq *ModelBuilder[T]) ChunkById(chunkSize int, column string, closure func(results []T) error, alias ...string) error {
+	return mq.QueryBuilder.ChunkById(chunkSize, column, mq.bindChunk("ChunkById", closure), alias...)
+}
+
+// ChunkByIdDesc processes results in batches of T using keyset pagination on the given column in descending order
+func (mq *ModelBuilder[T]) ChunkByIdDesc(chunkSize int, column string, closure func(results []T) error, alias ...string) error {
+	return mq.QueryBuilder.ChunkByIdDesc(chunkSize, column, mq.bindChunk("ChunkByIdDesc", closure), alias...)
+}
+
+// EachById calls the closure for each model while fetching the results in batches using keyset pagination
+func (mq *ModelBuilder[T]) EachById(chunkSize int, column string, closure func(model T) error, alias ...string) error {
+	return mq.ChunkById(chunkSize, column, func(results []T) error {
+		for _, model := range results {
+			if err := closure(model); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, alias...)
+}
+
+// ChunksParallel processes results of T in chunks concurrently using a pool of workers
+func (mq *ModelBuilder[T]) ChunksParallel(chunkSize int, workers int, closure func(results []T) error) error {
+	return mq.QueryBuilder.ChunksParallel(chunkSize, workers, mq.bindChunk("ChunksParallel", closure))
+}
+
+// ChunksParallelById processes results of T in chunks concurrently splitting the keyspace of the given column
+func (mq *ModelBuilder[T]) ChunksParallelById(chunkSize int, workers int, column string, closure func(results []T) error) error {
+	return mq.QueryBuilder.ChunksParallelById(chunkSize, workers, column, mq.bindChunk("ChunksParallelById", closure))
+}
+
+// bindChunk wraps a typed chunk closure into a closure over the raw results
+func (mq *ModelBuilder[T]) bindChunk(method string, closure func(results []T) error) func(results []map[string]any) error {
+	return func(results []map[string]any) error {
+		var models []T
+		if err := Bind(results, &models); err != nil {
+			return fmt.Errorf("%w: %s() failed to bind results: %v", ErrInvalidResult, method, err)
+		}
+		return closure(models)
+	}
+}
+
+// Cursor streams the results one at a time as type T keeping a single *sql.Rows open
+func (mq *ModelBuilder[T]) Cursor() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for data, err := range mq.QueryBuilder.Cursor() {
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			var model T
+			if err := Bind(data, &model); err != nil {
+				yield(zero, fmt.Errorf("%w: Cursor() failed to bind result: %v", ErrInvalidResult, err))
+				return
+			}
+
+			if !yield(model, nil) {
+				return
+			}
+		}
+	}
+}
+
 // QB provides access to raw QueryBuilder methods while maintaining type safety
 func (mq *ModelBuilder[T]) Q(fn func(*QueryBuilder) *QueryBuilder) *ModelBuilder[T] {
 	fn(mq.QueryBuilder)
@@ -0,0 +1,216 @@
+package xqb
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// Paginator holds a page of results with typed pagination metadata.
+// Total and LastPage are only set when HasTotal is true (Paginate with a count column),
+// SimplePaginate skips the COUNT query and only knows whether more pages exist.
+type Paginator[T any] struct {
+	Items       []T
+	PerPage     int
+	CurrentPage int
+	Total       int64
+	LastPage    int
+	HasTotal    bool
+
+	hasMore  bool // only used by the simple paginator (HasTotal false)
+	path     string
+	pageName string
+}
+
+// newPaginator creates a paginator for the given page of items
+func newPaginator[T any](items []T, perPage int, page int, hasMore bool) *Paginator[T] {
+	return &Paginator[T]{
+		Items:       items,
+		PerPage:     perPage,
+		CurrentPage: page,
+		hasMore:     hasMore,
+	}
+}
+
+// withTotal sets the total count of records and computes the last page
+func (p *Paginator[T]) withTotal(total int64) *Paginator[T] {
+	p.Total = total
+	p.HasTotal = true
+	p.LastPage = int((total + int64(p.PerPage) - 1) / int64(p.PerPage))
+	if p.LastPage < 1 {
+		p.LastPage = 1
+	}
+	return p
+}
+
+// mapPaginator returns a copy of the paginator holding the given items
+func mapPaginator[T any, U any](p *Paginator[T], items []U) *Paginator[U] {
+	return &Paginator[U]{
+		Items:       items,
+		PerPage:     p.PerPage,
+		CurrentPage: p.CurrentPage,
+		Total:       p.Total,
+		LastPage:    p.LastPage,
+		HasTotal:    p.HasTotal,
+		hasMore:     p.hasMore,
+		path:        p.path,
+		pageName:    p.pageName,
+	}
+}
+
+// WithPath sets the base url and the query parameter name used to generate the page links
+// Example: WithPath("https://api.example.com/users?sort=name", "page")
+func (p *Paginator[T]) WithPath(baseURL string, pageName string) *Paginator[T] {
+	p.path = baseURL
+	p.pageName = pageName
+	return p
+}
+
+// HasMorePages reports whether there are more pages after the current one,
+// computed from LastPage when the total is known
+func (p *Paginator[T]) HasMorePages() bool {
+	if p.HasTotal {
+		return p.CurrentPage < p.LastPage
+	}
+	return p.hasMore
+}
+
+// OnFirstPage reports whether the paginator is on the first page
+func (p *Paginator[T]) OnFirstPage() bool {
+	return p.CurrentPage <= 1
+}
+
+// Count returns the number of items on the current page
+func (p *Paginator[T]) Count() int {
+	return len(p.Items)
+}
+
+// From returns the position of the first item of the current page, zero when the page is empty
+func (p *Paginator[T]) From() int {
+	if len(p.Items) == 0 {
+		return 0
+	}
+	return (p.CurrentPage-1)*p.PerPage + 1
+}
+
+// To returns the position of the last item of the current page, zero when the page is empty
+func (p *Paginator[T]) To() int {
+	if len(p.Items) == 0 {
+		return 0
+	}
+	return p.From() + len(p.Items) - 1
+}
+
+// URL returns the link of the given page using the base url and page query parameter ("page" by default)
+func (p *Paginator[T]) URL(page int) string {
+	if page < 1 {
+		page = 1
+	}
+
+	u, err := url.Parse(p.path)
+	if err != nil {
+		return ""
+	}
+
+	pageName := p.pageName
+	if pageName == "" {
+		pageName = "page"
+	}
+
+	query := u.Query()
+	query.Set(pageName, strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// FirstPageURL returns the link of the first page
+func (p *Paginator[T]) FirstPageURL() string {
+	return p.URL(1)
+}
+
+// LastPageURL returns the link of the last page, empty when the total is unknown
+func (p *Paginator[T]) LastPageURL() string {
+	if !p.HasTotal {
+		return ""
+	}
+	return p.URL(p.LastPage)
+}
+
+// NextPageURL returns the link of the next page, empty when there are no more pages
+func (p *Paginator[T]) NextPageURL() string {
+	if !p.HasMorePages() {
+		return ""
+	}
+	return p.URL(p.CurrentPage + 1)
+}
+
+// PrevPageURL returns the link of the previous page, empty on the first page
+func (p *Paginator[T]) PrevPageURL() string {
+	if p.OnFirstPage() {
+		return ""
+	}
+	return p.URL(p.CurrentPage - 1)
+}
+
+type paginatorJSON[T any] struct {
+	Data  []T                `json:"data"`
+	Meta  paginatorMetaJSON  `json:"meta"`
+	Links paginatorLinksJSON `json:"links"`
+}
+
+type paginatorMetaJSON struct {
+	CurrentPage int    `json:"current_page"`
+	PerPage     int    `json:"per_page"`
+	From        int    `json:"from"`
+	To          int    `json:"to"`
+	Total       *int64 `json:"total,omitempty"`
+	LastPage    *int   `json:"last_page,omitempty"`
+}
+
+type paginatorLinksJSON struct {
+	First string  `json:"first"`
+	Last  *string `json:"last,omitempty"`
+	Prev  *string `json:"prev"`
+	Next  *string `json:"next"`
+}
+
+// MarshalJSON encodes the paginator as {"data": [...], "meta": {...}, "links": {...}}
+// total, last_page and the last link are omitted when the total is unknown
+func (p *Paginator[T]) MarshalJSON() ([]byte, error) {
+	data := p.Items
+	if data == nil {
+		data = []T{}
+	}
+
+	out := paginatorJSON[T]{
+		Data: data,
+		Meta: paginatorMetaJSON{
+			CurrentPage: p.CurrentPage,
+			PerPage:     p.PerPage,
+			From:        p.From(),
+			To:          p.To(),
+		},
+		Links: paginatorLinksJSON{
+			First: p.FirstPageURL(),
+			Prev:  optionalString(p.PrevPageURL()),
+			Next:  optionalString(p.NextPageURL()),
+		},
+	}
+
+	if p.HasTotal {
+		total, lastPage, last := p.Total, p.LastPage, p.LastPageURL()
+		out.Meta.Total = &total
+		out.Meta.LastPage = &lastPage
+		out.Links.Last = &last
+	}
+
+	return json.Marshal(out)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
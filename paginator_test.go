@@ -0,0 +1,60 @@
+package xqb_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Paginator_Links(t *testing.T) {
+	paginator := &xqb.Paginator[int]{
+		Items:       []int{4, 5, 6},
+		PerPage:     3,
+		CurrentPage: 2,
+	}
+	paginator.WithPath("https://api.example.com/users?sort=name", "p")
+
+	assert.False(t, paginator.HasMorePages())
+	assert.Equal(t, 4, paginator.From())
+	assert.Equal(t, 6, paginator.To())
+	assert.Equal(t, "https://api.example.com/users?p=1&sort=name", paginator.FirstPageURL())
+	assert.Equal(t, "https://api.example.com/users?p=1&sort=name", paginator.PrevPageURL())
+	assert.Equal(t, "", paginator.NextPageURL())
+	assert.Equal(t, "", paginator.LastPageURL())
+}
+
+func Test_Paginator_MarshalJSON(t *testing.T) {
+	paginator := &xqb.Paginator[map[string]any]{
+		Items:       []map[string]any{{"id": 1}, {"id": 2}},
+		PerPage:     2,
+		CurrentPage: 1,
+		Total:       5,
+		LastPage:    3,
+		HasTotal:    true,
+	}
+	paginator.WithPath("/users", "")
+
+	data, err := json.Marshal(paginator)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"data": [{"id": 1}, {"id": 2}],
+		"meta": {"current_page": 1, "per_page": 2, "from": 1, "to": 2, "total": 5, "last_page": 3},
+		"links": {"first": "/users?page=1", "last": "/users?page=3", "prev": null, "next": "/users?page=2"}
+	}`, string(data))
+}
+
+func Test_Paginator_MarshalJSON_WithoutTotal(t *testing.T) {
+	paginator := &xqb.Paginator[int]{PerPage: 10, CurrentPage: 1}
+
+	data, err := json.Marshal(paginator)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"data": [],
+		"meta": {"current_page": 1, "per_page": 10, "from": 0, "to": 0},
+		"links": {"first": "?page=1", "prev": null, "next": null}
+	}`, string(data))
+}
@@ -0,0 +1,404 @@
+package xqb
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iMohamedSheta/xqb/shared/enums"
+)
+
+// DefaultCacheSize is the capacity of the memory cache created when the settings have no cache store
+const DefaultCacheSize = 1000
+
+// CacheStore stores the cached query results, implement it with Redis or any other store to share them.
+// The keys are derived from the manager, the dialect, the connection, the sql and the bindings of the statement,
+// the default manager keys don't depend on the process so they can be shared.
+type CacheStore interface {
+	// Get returns the cached rows of the key, false when they're missing or expired
+	Get(ctx context.Context, key string) (*BufferedRows, bool, error)
+	// Set stores the rows for the ttl (forever when ttl <= 0) and indexes them under the tags
+	Set(ctx context.Context, key string, rows *BufferedRows, ttl time.Duration, tags []string) error
+	// InvalidateTags removes the rows stored under any of the tags
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// queryCache holds the cache options of a query
+type queryCache struct {
+	ttl  time.Duration
+	tags []string
+}
+
+// Cache caches the results of the query for the ttl (forever when ttl <= 0) in the cache store of the settings.
+// The results are tagged with the table name and the given tags, xqb writes (Insert/Update/Delete) on the
+// table invalidate them. Concurrent misses of the same query run it once.
+// Queries running in a transaction or with a lock are never cached.
+//
+// Example:
+//
+//	users, err := xqb.Table("users").Where("active", "=", true).Cache(time.Minute, "active_users").Get()
+func (qb *QueryBuilder) Cache(ttl time.Duration, tags ...string) *QueryBuilder {
+	qb.cache = &queryCache{ttl: ttl, tags: tags}
+	return qb
+}
+
+// Cache caches the results of a read statement for the ttl, raw statements are only tagged with the given tags
+// and raw writes don't invalidate anything (see QueryBuilder.Cache).
+func (s *SqlQuery) Cache(ttl time.Duration, tags ...string) *SqlQuery {
+	s.cache = &queryCache{ttl: ttl, tags: tags}
+	return s
+}
+
+// SetCacheStore sets the store of the cached query results
+func (s *QueryBuilderSettings) SetCacheStore(store CacheStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheStore = store
+}
+
+// GetCacheStore returns the store of the cached query results, a memory cache of DefaultCacheSize
+// entries is created when none was set.
+func (s *QueryBuilderSettings) GetCacheStore() CacheStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cacheStore == nil {
+		s.cacheStore = NewMemoryCache(DefaultCacheSize)
+	}
+	return s.cacheStore
+}
+
+// InvalidateCache removes the cached results of the tags from the cache store of the settings,
+// the misses of the tags running meanwhile don't store their rows.
+func (s *QueryBuilderSettings) InvalidateCache(ctx context.Context, tags ...string) error {
+	s.cacheEpochs.advance(tags)
+
+	s.mu.RLock()
+	store := s.cacheStore
+	s.mu.RUnlock()
+
+	if store == nil || len(tags) == 0 {
+		return nil
+	}
+	return store.InvalidateTags(ctx, tags...)
+}
+
+// InvalidateCache removes the cached results of the tags from the cache store of the default settings
+func InvalidateCache(ctx context.Context, tags ...string) error {
+	return DefaultSettings().InvalidateCache(ctx, tags...)
+}
+
+// TableTag returns the cache tag of a table, the table name without its alias
+func TableTag(table string) string {
+	fields := strings.Fields(table)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.Trim(fields[0], "`\"")
+}
+
+// isCacheable reports whether the results of the statement can be served from the cache
+func (s *SqlQuery) isCacheable(kind QueryKind, event *QueryEvent) bool {
+	if s.cache == nil || kind == QueryKindExecute || event.Tx != nil || s.locked {
+		return false
+	}
+	queryType := s.queryType
+	if queryType == 0 {
+		queryType = inferQueryType(s.sql)
+	}
+	return queryType == enums.SELECT
+}
+
+// cacheTags returns the tags of the cached results, the table tag then the given tags
+func (s *SqlQuery) cacheTags() []string {
+	tags := make([]string, 0, len(s.cache.tags)+1)
+	if tag := TableTag(s.table); tag != "" {
+		tags = append(tags, tag)
+	}
+	return append(tags, s.cache.tags...)
+}
+
+// cached wraps the round trip with the cache store, a miss buffers the rows, stores them and serves the buffer.
+// The store is best effort: its errors make the statement run on the database.
+func (s *SqlQuery) cached(next QueryHandler) QueryHandler {
+	return func(ctx context.Context, event *QueryEvent) (Result, error) {
+		if event.Tx != nil {
+			// moved into a transaction by an interceptor
+			return next(ctx, event)
+		}
+
+		settings := s.getSettings()
+		store := settings.GetCacheStore()
+		key := event.getManager().cacheKey(event)
+		tags := s.cacheTags()
+
+		if rows, ok, err := store.Get(ctx, key); err == nil && ok {
+			return rows.Result()
+		}
+
+		rows, err := settings.cacheCalls.do(key, func() (*BufferedRows, error) {
+			// a call finishing after the Get above already stored the rows
+			if rows, ok, err := store.Get(ctx, key); err == nil && ok {
+				return rows, nil
+			}
+
+			epoch := settings.cacheEpochs.current(tags)
+			result, err := next(ctx, event)
+			if err != nil {
+				return nil, err
+			}
+			rows, err := BufferRows(result.Rows)
+			if err != nil {
+				return nil, err
+			}
+
+			// a write invalidating the tags while the query ran may have changed the rows
+			if settings.cacheEpochs.current(tags) != epoch {
+				return rows, nil
+			}
+			_ = store.Set(ctx, key, rows, s.cache.ttl, tags)
+			if settings.cacheEpochs.current(tags) != epoch {
+				// invalidated between the check and the Set
+				_ = store.InvalidateTags(ctx, tags...)
+			}
+			return rows, nil
+		})
+		if err != nil {
+			return Result{}, err
+		}
+
+		return rows.Result()
+	}
+}
+
+// invalidateTableCache invalidates the cached results of the written table, again after the commit
+// when the write runs in a transaction since other connections may cache the old rows until then.
+func (s *SqlQuery) invalidateTableCache(event *QueryEvent) {
+	tag := TableTag(s.table)
+	if tag == "" {
+		return
+	}
+
+	settings := s.getSettings()
+	_ = settings.InvalidateCache(s.ctx, tag)
+
+	if event.Tx != nil {
+		if handle, ok := LookupTx(event.Tx); ok {
+			handle.AfterCommit(func() {
+				_ = settings.InvalidateCache(context.Background(), tag)
+			})
+		}
+	}
+}
+
+// cacheKey returns the cache key of the statement on its connection
+func (m *DBM) cacheKey(event *QueryEvent) string {
+	dialect := ""
+	if connDialect, err := m.GetDialect(event.Connection); err == nil {
+		dialect = string(connDialect.MappedDialect())
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s", m.cacheNamespace, dialect, event.Connection, event.Sql)
+	for _, binding := range event.Bindings {
+		fmt.Fprintf(hash, "\x00%T:%v", binding, binding)
+	}
+
+	return "xqb:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// cacheEpochs counts the invalidations of the tags, a miss only stores its rows when
+// none of its tags were invalidated while it ran
+type cacheEpochs struct {
+	mu     sync.Mutex
+	epochs map[string]uint64
+}
+
+// advance records an invalidation of the tags
+func (e *cacheEpochs) advance(tags []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.epochs == nil {
+		e.epochs = make(map[string]uint64)
+	}
+	for _, tag := range tags {
+		e.epochs[tag]++
+	}
+}
+
+// current returns the sum of the invalidations of the tags, it only grows
+func (e *cacheEpochs) current(tags []string) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var epoch uint64
+	for _, tag := range tags {
+		epoch += e.epochs[tag]
+	}
+	return epoch
+}
+
+// cacheGroup collapses the concurrent misses of the same key into one execution
+type cacheGroup struct {
+	mu    sync.Mutex
+	calls map[string]*cacheCall
+}
+
+type cacheCall struct {
+	done chan struct{}
+	rows *BufferedRows
+	err  error
+}
+
+// do runs fn once for the concurrent callers of the key, they all get its result
+func (g *cacheGroup) do(key string, fn func() (*BufferedRows, error)) (*BufferedRows, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.rows, call.err
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*cacheCall)
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.rows, call.err = fn()
+	return call.rows, call.err
+}
+
+// MemoryCache is an in-memory LRU CacheStore, it's safe for concurrent use
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // most recently used first
+	tags     map[string]map[string]struct{}
+}
+
+type memoryCacheEntry struct {
+	key     string
+	rows    *BufferedRows
+	expires time.Time // zero when the entry never expires
+	tags    []string
+}
+
+// NewMemoryCache creates a memory cache holding up to capacity results (DefaultCacheSize when capacity <= 0)
+func NewMemoryCache(capacity int) *MemoryCache {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+	return &MemoryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		tags:     make(map[string]map[string]struct{}),
+	}
+}
+
+// Get returns the cached rows of the key
+func (c *MemoryCache) Get(_ context.Context, key string) (*BufferedRows, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.rows, true, nil
+}
+
+// Set stores the rows of the key, the least recently used entry is evicted when the cache is full
+func (c *MemoryCache) Set(_ context.Context, key string, rows *BufferedRows, ttl time.Duration, tags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	entry := &memoryCacheEntry{key: key, rows: rows, tags: append([]string(nil), tags...)}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for _, tag := range entry.tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+// InvalidateTags removes the entries stored under any of the tags
+func (c *MemoryCache) InvalidateTags(_ context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if element, ok := c.entries[key]; ok {
+				c.remove(element)
+			}
+		}
+		delete(c.tags, tag)
+	}
+
+	return nil
+}
+
+// Len returns the number of cached results, expired ones included until they're read or evicted
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Clear removes all the cached results
+func (c *MemoryCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.tags = make(map[string]map[string]struct{})
+}
+
+// remove deletes the entry from the cache and from its tags
+func (c *MemoryCache) remove(element *list.Element) {
+	entry := element.Value.(*memoryCacheEntry)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+	for _, tag := range entry.tags {
+		if keys := c.tags[tag]; keys != nil {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
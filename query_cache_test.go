@@ -0,0 +1,224 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCacheSettings returns settings with their own memory cache so the tests don't share cached results
+func newCacheSettings() (*xqb.QueryBuilderSettings, *xqb.MemoryCache) {
+	settings := xqb.NewQueryBuilderSettings()
+	store := xqb.NewMemoryCache(10)
+	settings.SetCacheStore(store)
+	return settings, store
+}
+
+func Test_Cache_ServesRepeatedReadsFromTheStore(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(2), nil
+	})
+	settings, store := newCacheSettings()
+
+	for range 3 {
+		users, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).
+			Where("active", "=", true).Cache(time.Minute).Get()
+		assert.NoError(t, err)
+		assert.Len(t, users, 2)
+		assert.Equal(t, int64(2), users[1]["id"])
+	}
+
+	// other bindings are another key
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).
+		Where("active", "=", false).Cache(time.Minute).Get()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"SELECT * FROM `users` WHERE `active` = ?",
+		"SELECT * FROM `users` WHERE `active` = ?",
+	}, fdb.Statements())
+	assert.Equal(t, 2, store.Len())
+	assert.Equal(t, 0, fdb.OpenRows())
+}
+
+func Test_Cache_WritesInvalidateTheTable(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	settings, store := newCacheSettings()
+
+	read := func() {
+		_, err := xqb.Table("users as u").Connection(fdb.name).WithSettings(settings).Cache(0, "members").Get()
+		assert.NoError(t, err)
+	}
+
+	read()
+	read()
+	assert.Equal(t, 1, store.Len())
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).
+		Where("id", "=", 1).Update(map[string]any{"name": "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, store.Len())
+
+	read()
+	assert.NoError(t, settings.InvalidateCache(context.Background(), "members"))
+	assert.Equal(t, 0, store.Len())
+	read()
+
+	assert.Len(t, fdb.Statements(), 4, "3 reads and the update")
+}
+
+func Test_Cache_CollapsesConcurrentMisses(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		started <- struct{}{}
+		<-release
+		return usersRows(3), nil
+	})
+	settings, _ := newCacheSettings()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Cache(time.Minute).Get()
+			assert.NoError(t, err)
+			assert.Len(t, users, 3)
+		}()
+	}
+
+	<-started
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Len(t, fdb.Statements(), 1)
+}
+
+func Test_Cache_SkipsTransactions(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	settings, store := newCacheSettings()
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		for range 2 {
+			if _, err := xqb.Table("users").Connection(fdb.name).WithContext(ctx).WithSettings(settings).Cache(time.Minute).Get(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, fdb.TxStatements(), 2)
+	assert.Equal(t, 0, store.Len())
+}
+
+func Test_Cache_Model(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	settings, _ := newCacheSettings()
+
+	for range 2 {
+		users, err := xqb.Model[User]().Connection(fdb.name).WithSettings(settings).Cache(time.Minute).Get()
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+	}
+
+	assert.Len(t, fdb.Statements(), 1)
+}
+
+func Test_MemoryCache_EvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	store := xqb.NewMemoryCache(2)
+	rows := &xqb.BufferedRows{Columns: []string{"id"}}
+
+	assert.NoError(t, store.Set(ctx, "a", rows, 0, []string{"users"}))
+	assert.NoError(t, store.Set(ctx, "b", rows, 0, nil))
+	_, _, _ = store.Get(ctx, "a")
+	assert.NoError(t, store.Set(ctx, "c", rows, time.Millisecond, nil))
+
+	_, ok, _ := store.Get(ctx, "b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	_, ok, _ = store.Get(ctx, "a")
+	assert.True(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = store.Get(ctx, "c")
+	assert.False(t, ok, "expired entry")
+
+	assert.NoError(t, store.InvalidateTags(ctx, "users"))
+	assert.Equal(t, 0, store.Len())
+}
+
+func Test_Cache_ManagersDontShareResults(t *testing.T) {
+	first, firstDB := newFakeManager(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	second, secondDB := newFakeManager(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(2), nil
+	})
+	settings, _ := newCacheSettings()
+
+	users, err := first.Table("users").WithSettings(settings).Cache(time.Minute).Get()
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	// same connection name and sql on another manager
+	users, err = second.Table("users").WithSettings(settings).Cache(time.Minute).Get()
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	assert.Len(t, firstDB.Statements(), 1)
+	assert.Len(t, secondDB.Statements(), 1)
+}
+
+func Test_Cache_WriteDuringMissIsNotOverwritten(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var reads sync.Once
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		if query == "SELECT * FROM `users`" {
+			// only the first read waits for the write
+			reads.Do(func() {
+				close(started)
+				<-release
+			})
+			return usersRows(1), nil
+		}
+		return &fakeResult{rowsAffected: 1}, nil
+	})
+	settings, store := newCacheSettings()
+	read := func() {
+		_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Cache(time.Minute).Get()
+		assert.NoError(t, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		read()
+	}()
+
+	<-started
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("id", "=", 1).Delete()
+	assert.NoError(t, err)
+	close(release)
+	<-done
+
+	// the rows read before the write aren't cached
+	assert.Equal(t, 0, store.Len())
+	read()
+	assert.Equal(t, 1, store.Len())
+	assert.Len(t, fdb.Statements(), 3)
+}
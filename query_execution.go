@@ -0,0 +1,116 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iMohamedSheta/xqb/shared/enums"
+)
+
+// QueryExecution is the event emitted after a statement was executed on the database (see OnQueryExecuted).
+// Unlike QueryExecuted (emitted after the query is built) it holds the real round trip metrics.
+type QueryExecution struct {
+	Sql        string
+	Bindings   []any
+	Connection string
+	Kind       QueryKind
+	QueryType  enums.QueryType // inferred from the first keyword of raw queries, zero when unknown
+	Table      string          // empty for raw queries
+	Duration   time.Duration   // wall clock time, including reading the rows for builder queries
+	Rows       int64           // rows affected (Execute) or returned (Query/QueryRow), -1 when unknown
+	Err        error
+	InTx       bool
+	Context    context.Context
+
+	db *sql.DB // the primary or the read replica that ran the statement, nil when it didn't reach the database
+}
+
+// OnQueryExecuted adds a listener called after every statement executed on the database,
+// the listeners are called in the order they were added.
+func (s *QueryBuilderSettings) OnQueryExecuted(listener func(execution *QueryExecution)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onQueryExecuted = append(s.onQueryExecuted, listener)
+}
+
+// GetOnQueryExecuted returns the listeners called after every statement executed on the database
+func (s *QueryBuilderSettings) GetOnQueryExecuted() []func(execution *QueryExecution) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.onQueryExecuted)
+}
+
+// ClearOnQueryExecuted removes all the query executed listeners
+func (s *QueryBuilderSettings) ClearOnQueryExecuted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onQueryExecuted = nil
+}
+
+// newExecution creates the execution event of the statement sent by the interceptor chain
+func (s *SqlQuery) newExecution(event *QueryEvent) *QueryExecution {
+	queryType := s.queryType
+	if queryType == 0 {
+		queryType = inferQueryType(event.Sql)
+	}
+
+	return &QueryExecution{
+		Sql:        event.Sql,
+		Bindings:   event.Bindings,
+		Connection: event.Connection,
+		Kind:       event.Kind,
+		QueryType:  queryType,
+		Table:      s.table,
+		Rows:       -1,
+		InTx:       event.Tx != nil,
+		db:         event.db,
+		Context:    s.ctx,
+		Duration:   time.Since(s.started),
+	}
+}
+
+// emitExecution calls the query executed listeners and the slow query log of the settings
+func (s *SqlQuery) emitExecution(execution *QueryExecution) {
+	for _, listener := range s.getSettings().GetOnQueryExecuted() {
+		safeCall(func() {
+			listener(execution)
+		})
+	}
+
+	s.logSlowQuery(execution)
+}
+
+// finish emits the execution event of a query whose rows were read by the builder (see withRowsCount)
+func (s *SqlQuery) finish(rows int64, err error) {
+	if s.pending == nil {
+		return
+	}
+
+	execution := s.pending
+	s.pending = nil
+	execution.Duration = time.Since(s.started)
+	execution.Rows = rows
+	execution.Err = err
+
+	s.emitExecution(execution)
+}
+
+// inferQueryType returns the query type from the first keyword of a raw statement
+func inferQueryType(query string) enums.QueryType {
+	keyword, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	switch strings.ToUpper(keyword) {
+	case "SELECT", "WITH":
+		return enums.SELECT
+	case "INSERT":
+		return enums.INSERT
+	case "UPDATE":
+		return enums.UPDATE
+	case "DELETE":
+		return enums.DELETE
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,138 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/iMohamedSheta/xqb/shared/enums"
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordExecutions returns settings collecting the query execution events
+func recordExecutions() (*xqb.QueryBuilderSettings, *[]*xqb.QueryExecution) {
+	var executions []*xqb.QueryExecution
+	settings := xqb.NewQueryBuilderSettings()
+	settings.OnQueryExecuted(func(execution *xqb.QueryExecution) {
+		executions = append(executions, execution)
+	})
+	return settings, &executions
+}
+
+func Test_QueryExecuted_Get(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(3), nil
+	})
+	settings, executions := recordExecutions()
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("id", ">", 1).Get()
+
+	assert.NoError(t, err)
+	assert.Len(t, *executions, 1)
+	execution := (*executions)[0]
+	assert.Equal(t, "SELECT * FROM `users` WHERE `id` > ?", execution.Sql)
+	assert.Equal(t, []any{1}, execution.Bindings)
+	assert.Equal(t, fdb.name, execution.Connection)
+	assert.Equal(t, xqb.QueryKindQuery, execution.Kind)
+	assert.Equal(t, enums.SELECT, execution.QueryType)
+	assert.Equal(t, "SELECT", execution.QueryType.String())
+	assert.Equal(t, "users", execution.Table)
+	assert.Equal(t, int64(3), execution.Rows)
+	assert.Positive(t, execution.Duration)
+	assert.NoError(t, execution.Err)
+	assert.False(t, execution.InTx)
+}
+
+func Test_QueryExecuted_ExecuteReportsAffectedRows(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return &fakeResult{rowsAffected: 4}, nil
+	})
+	settings, executions := recordExecutions()
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("active", "=", false).Delete()
+
+	assert.NoError(t, err)
+	assert.Len(t, *executions, 1)
+	assert.Equal(t, enums.DELETE, (*executions)[0].QueryType)
+	assert.Equal(t, xqb.QueryKindExecute, (*executions)[0].Kind)
+	assert.Equal(t, int64(4), (*executions)[0].Rows)
+}
+
+func Test_QueryExecuted_ReportsError(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return nil, &mysqlError{Number: 1062, Message: "Duplicate entry 'a' for key 'users.email'"}
+	})
+	settings, executions := recordExecutions()
+
+	err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Insert([]map[string]any{{"email": "a"}})
+
+	assert.Error(t, err)
+	assert.Len(t, *executions, 1)
+	assert.ErrorIs(t, (*executions)[0].Err, xqbErr.ErrUniqueViolation)
+	assert.Equal(t, int64(-1), (*executions)[0].Rows)
+}
+
+func Test_QueryExecuted_CursorCountsReadRows(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(5), nil
+	})
+	settings, executions := recordExecutions()
+
+	for range xqb.Table("users").Connection(fdb.name).WithSettings(settings).Cursor() {
+		assert.Empty(t, *executions, "the event is emitted when the loop ends")
+		break
+	}
+
+	assert.Len(t, *executions, 1)
+	assert.Equal(t, int64(1), (*executions)[0].Rows)
+}
+
+func Test_QueryExecuted_RawSqlInTransaction(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, func(query string, args []any) (*fakeResult, error) {
+		if query == "SELECT name FROM users WHERE id = $1" {
+			return &fakeResult{columns: []string{"name"}}, nil
+		}
+		return &fakeResult{rowsAffected: 1}, nil
+	})
+	settings, executions := recordExecutions()
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, nil, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := xqb.Sql("UPDATE users SET name = $1", "bob").Connection(fdb.name).WithSettings(settings).WithContext(ctx).Execute(); err != nil {
+			return err
+		}
+		var name string
+		err := xqb.Sql("SELECT name FROM users WHERE id = $1", 1).Connection(fdb.name).WithSettings(settings).WithContext(ctx).QueryRow(&name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, *executions, 2)
+	assert.Equal(t, enums.UPDATE, (*executions)[0].QueryType)
+	assert.Empty(t, (*executions)[0].Table)
+	assert.True(t, (*executions)[0].InTx)
+	assert.Equal(t, enums.SELECT, (*executions)[1].QueryType)
+	assert.Equal(t, xqb.QueryKindQueryRow, (*executions)[1].Kind)
+	assert.Equal(t, int64(0), (*executions)[1].Rows)
+	assert.ErrorIs(t, (*executions)[1].Err, sql.ErrNoRows)
+}
+
+func Test_QueryExecuted_SeesInterceptedSql(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	settings, executions := recordExecutions()
+	settings.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		event.Sql = "/* app */ " + event.Sql
+		return next(ctx, event)
+	})
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("id", "=", 1).Delete()
+
+	assert.NoError(t, err)
+	assert.Len(t, *executions, 1)
+	assert.Equal(t, "/* app */ DELETE FROM `users` WHERE `id` = ?", (*executions)[0].Sql)
+}
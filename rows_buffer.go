@@ -0,0 +1,153 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	xqbErr "github.com/iMohamedSheta/xqb/shared/errors"
+)
+
+// BufferedRows is a result set held in memory, interceptors use it to cache or replay query results
+// since *sql.Rows can only be read once and can't be created outside database/sql.
+type BufferedRows struct {
+	Columns []string
+	Values  [][]any
+}
+
+// BufferRows reads the remaining rows into memory and closes them
+func BufferRows(rows *sql.Rows) (*BufferedRows, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("%w: BufferRows() failed to retrieve columns %w", xqbErr.ErrInvalidResult, err)
+	}
+
+	buffered := &BufferedRows{Columns: columns}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		valuePtrs := make([]any, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, fmt.Errorf("%w: BufferRows() failed to scan result rows %w", xqbErr.ErrInvalidResult, err)
+		}
+		buffered.Values = append(buffered.Values, values)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buffered, nil
+}
+
+// Rows returns a new *sql.Rows reading the buffered values, it can be called any number of times
+func (b *BufferedRows) Rows() (*sql.Rows, error) {
+	return memoryDB().QueryContext(context.Background(), "", b)
+}
+
+// Result returns an interceptor result serving the buffered values
+func (b *BufferedRows) Result() (Result, error) {
+	rows, err := b.Rows()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Rows: rows}, nil
+}
+
+// memoryDB is the database serving BufferedRows through database/sql
+var memoryDB = sync.OnceValue(func() *sql.DB {
+	return sql.OpenDB(memoryConnector{})
+})
+
+type memoryConnector struct{}
+
+func (c memoryConnector) Connect(context.Context) (driver.Conn, error) {
+	return memoryConn{}, nil
+}
+
+func (c memoryConnector) Driver() driver.Driver {
+	return memoryDriver{}
+}
+
+type memoryDriver struct{}
+
+func (d memoryDriver) Open(string) (driver.Conn, error) {
+	return memoryConn{}, nil
+}
+
+// memoryConn answers every query with the *BufferedRows passed as its only argument
+type memoryConn struct{}
+
+func (c memoryConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("xqb: buffered rows don't support prepared statements")
+}
+
+func (c memoryConn) Close() error {
+	return nil
+}
+
+func (c memoryConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("xqb: buffered rows don't support transactions")
+}
+
+// CheckNamedValue accepts the *BufferedRows argument as is
+func (c memoryConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c memoryConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, errors.New("xqb: buffered rows query expects one argument")
+	}
+
+	buffered, ok := args[0].Value.(*BufferedRows)
+	if !ok || buffered == nil {
+		return nil, errors.New("xqb: buffered rows query expects a *BufferedRows argument")
+	}
+
+	return &memoryRows{buffered: buffered}, nil
+}
+
+type memoryRows struct {
+	buffered *BufferedRows
+	pos      int
+}
+
+func (r *memoryRows) Columns() []string {
+	return r.buffered.Columns
+}
+
+func (r *memoryRows) Close() error {
+	return nil
+}
+
+func (r *memoryRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.buffered.Values) {
+		return io.EOF
+	}
+
+	row := r.buffered.Values[r.pos]
+	r.pos++
+
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		value, err := driver.DefaultParameterConverter.ConvertValue(row[i])
+		if err != nil {
+			return fmt.Errorf("xqb: buffered rows column %q: %w", r.buffered.Columns[i], err)
+		}
+		dest[i] = value
+	}
+
+	return nil
+}
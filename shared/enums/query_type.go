@@ -22,3 +22,19 @@ const (
 	CLAUSE_CTE      QueryType = 17
 	CLAUSE_LOCKING  QueryType = 18
 )
+
+// String returns the statement keyword of the query type, empty for clauses
+func (q QueryType) String() string {
+	switch q {
+	case SELECT:
+		return "SELECT"
+	case INSERT:
+		return "INSERT"
+	case UPDATE:
+		return "UPDATE"
+	case DELETE:
+		return "DELETE"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,101 @@
+package errors
+
+import "errors"
+
+// DriverError holds the vendor error number, SQLSTATE code and the object names reported by a database driver
+type DriverError struct {
+	Number     int    // vendor error number (e.g. MySql 1213), zero when unknown
+	SQLState   string // five characters SQLSTATE code (e.g. Postgres 40P01), empty when unknown
+	Constraint string
+	Table      string
+	Column     string
+}
+
+// DBError is a database error translated by the dialect, errors.Is matches both its Kind sentinel
+// (e.g. ErrUniqueViolation) and the original driver error. Constraint, Table and Column are set
+// when the driver reports them or they can be read from the error message.
+type DBError struct {
+	Kind       error
+	Number     int
+	SQLState   string
+	Constraint string
+	Table      string
+	Column     string
+	Err        error
+}
+
+// NewDBError creates the DBError of the given kind from the driver error details
+func NewDBError(kind error, driverErr DriverError, err error) *DBError {
+	return &DBError{
+		Kind:       kind,
+		Number:     driverErr.Number,
+		SQLState:   driverErr.SQLState,
+		Constraint: driverErr.Constraint,
+		Table:      driverErr.Table,
+		Column:     driverErr.Column,
+		Err:        err,
+	}
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// NumberedError is implemented by driver errors exposing a vendor error number (e.g. MySql 1213),
+// wrap the errors of a custom driver with it to get them translated.
+type NumberedError interface {
+	error
+	ErrorNumber() int
+}
+
+// sqlStateError is implemented by *pgconn.PgError of pgx and *pq.Error of lib/pq
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// fieldsError is implemented by *pq.Error of lib/pq, the fields are read by their protocol code
+type fieldsError interface {
+	error
+	Get(field byte) string
+}
+
+// ParseDriverError extracts the vendor error number, SQLSTATE code and object names from a driver error
+// by its methods: NumberedError, SQLState() string (e.g. *pgconn.PgError, *pq.Error) and the fields of *pq.Error.
+// The dialects read the error types of their drivers (*mysql.MySQLError, *pgconn.PgError) before it.
+func ParseDriverError(err error) (DriverError, bool) {
+	var found DriverError
+	if err == nil {
+		return found, false
+	}
+
+	var numbered NumberedError
+	if errors.As(err, &numbered) {
+		found.Number = numbered.ErrorNumber()
+	}
+
+	var stated sqlStateError
+	if errors.As(err, &stated) {
+		found.SQLState = stated.SQLState()
+	}
+
+	var fields fieldsError
+	if errors.As(err, &fields) {
+		setIfEmpty(&found.SQLState, fields.Get('C'))
+		setIfEmpty(&found.Constraint, fields.Get('n'))
+		setIfEmpty(&found.Table, fields.Get('t'))
+		setIfEmpty(&found.Column, fields.Get('c'))
+	}
+
+	return found, found.Number != 0 || found.SQLState != ""
+}
+
+func setIfEmpty(target *string, value string) {
+	if *target == "" {
+		*target = value
+	}
+}
@@ -36,4 +36,34 @@ var (
 
 	// ErrClosingConnection is returned when a database connection could not be closed.
 	ErrClosingConnection = errors.New("xqb_failed_to_close_connection")
+
+	// ErrInvalidConfig is returned when a connection config is incomplete, duplicated or can't be parsed.
+	ErrInvalidConfig = errors.New("xqb_invalid_config")
+)
+
+// Database Errors, the dialects translate the driver errors into a *DBError matching one of them
+var (
+	// ErrUniqueViolation is returned when an insert or update violates a unique constraint or primary key.
+	ErrUniqueViolation = errors.New("xqb_unique_violation")
+
+	// ErrForeignKeyViolation is returned when a statement violates a foreign key constraint.
+	ErrForeignKeyViolation = errors.New("xqb_foreign_key_violation")
+
+	// ErrNotNullViolation is returned when a NULL value is written to a NOT NULL column.
+	ErrNotNullViolation = errors.New("xqb_not_null_violation")
+
+	// ErrCheckViolation is returned when a statement violates a CHECK constraint.
+	ErrCheckViolation = errors.New("xqb_check_violation")
+
+	// ErrDeadlock is returned when the transaction was chosen as a deadlock victim and rolled back.
+	ErrDeadlock = errors.New("xqb_deadlock")
+
+	// ErrLockTimeout is returned when a lock couldn't be acquired in time or immediately (NOWAIT).
+	ErrLockTimeout = errors.New("xqb_lock_timeout")
+
+	// ErrSerialization is returned when a serializable transaction couldn't be serialized with concurrent ones.
+	ErrSerialization = errors.New("xqb_serialization_failure")
+
+	// ErrConnectionLost is returned when the connection to the database server was lost.
+	ErrConnectionLost = errors.New("xqb_connection_lost")
 )
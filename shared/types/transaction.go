@@ -0,0 +1,16 @@
+package types
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TxOptions holds the options used to start a transaction.
+// Isolation and ReadOnly are passed to database/sql, the dialect compiles the remaining options
+// into statements executed right after the transaction begins.
+type TxOptions struct {
+	Isolation  sql.IsolationLevel
+	ReadOnly   bool
+	Deferrable bool          // Postgres only, used with SERIALIZABLE READ ONLY transactions
+	Timeout    time.Duration // rolls back the transaction when it takes longer than the timeout
+}
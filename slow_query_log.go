@@ -0,0 +1,196 @@
+package xqb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/iMohamedSheta/xqb/dialects"
+	"github.com/iMohamedSheta/xqb/shared/enums"
+	"github.com/iMohamedSheta/xqb/shared/types"
+)
+
+// LogSlowQueries enables the slow query log, statements slower than the threshold are logged with a
+// warning record containing the sql with the injected bindings, the duration and the rows. SELECT statements
+// outside a transaction are explained (EXPLAIN for MySql, EXPLAIN (FORMAT JSON) for Postgres) in the background
+// on the database that ran them (the primary or the read replica) and the plan is added to the record.
+// Statements of a transaction aren't explained so a failed explain can't abort the transaction, and at most
+// maxSlowQueryExplains explains run at once, the records of the other slow queries are logged without a plan.
+// The logger defaults to slog.Default() and a threshold <= 0 disables it.
+//
+// Example:
+//
+//	xqb.DefaultSettings().LogSlowQueries(500*time.Millisecond, slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+func (s *QueryBuilderSettings) LogSlowQueries(threshold time.Duration, logger *slog.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slowQueryThreshold = threshold
+	s.slowQueryLogger = logger
+}
+
+// GetSlowQueryLog returns the threshold and the logger of the slow query log
+func (s *QueryBuilderSettings) GetSlowQueryLog() (time.Duration, *slog.Logger) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slowQueryThreshold, s.slowQueryLogger
+}
+
+// defaultSlowQueryExplainTimeout bounds the explain statement of a slow query when no timeout is set
+const defaultSlowQueryExplainTimeout = 2 * time.Second
+
+// maxSlowQueryExplains bounds the explain statements running at once for the slow queries of the settings
+const maxSlowQueryExplains = 4
+
+// SetSlowQueryExplainTimeout sets the timeout of the explain statement of a slow query (2s by default),
+// a negative timeout disables the explain
+func (s *QueryBuilderSettings) SetSlowQueryExplainTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slowQueryExplain = timeout
+}
+
+// GetSlowQueryExplainTimeout returns the timeout of the explain statement of a slow query
+func (s *QueryBuilderSettings) GetSlowQueryExplainTimeout() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.slowQueryExplain == 0 {
+		return defaultSlowQueryExplainTimeout
+	}
+	return s.slowQueryExplain
+}
+
+// acquireSlowQueryExplain reserves one of the explains running at once, false when all of them are taken
+func (s *QueryBuilderSettings) acquireSlowQueryExplain() bool {
+	if s.slowQueryExplains.Add(1) > maxSlowQueryExplains {
+		s.slowQueryExplains.Add(-1)
+		return false
+	}
+	return true
+}
+
+// releaseSlowQueryExplain frees the explain reserved by acquireSlowQueryExplain
+func (s *QueryBuilderSettings) releaseSlowQueryExplain() {
+	s.slowQueryExplains.Add(-1)
+}
+
+// logSlowQuery logs the execution when it exceeds the slow query threshold of the settings
+func (s *SqlQuery) logSlowQuery(execution *QueryExecution) {
+	threshold, logger := s.getSettings().GetSlowQueryLog()
+	if threshold <= 0 || execution.Duration < threshold {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	dialect := types.DialectMySql
+	if connDialect, err := s.getManager().GetDialect(execution.Connection); err == nil {
+		dialect = connDialect.MappedDialect()
+	}
+
+	rawSql, err := InjectBindings(dialect, execution.Sql, execution.Bindings)
+	if err != nil {
+		rawSql = execution.Sql
+	}
+
+	attrs := []slog.Attr{
+		slog.String("connection", execution.Connection),
+		slog.String("sql", rawSql),
+		slog.Duration("duration", execution.Duration),
+		slog.Int64("rows", execution.Rows),
+	}
+	if execution.Table != "" {
+		attrs = append(attrs, slog.String("table", execution.Table))
+	}
+	if execution.Err != nil {
+		attrs = append(attrs, slog.String("error", execution.Err.Error()))
+	}
+
+	ctx := execution.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	settings := s.getSettings()
+	timeout := settings.GetSlowQueryExplainTimeout()
+
+	// an explain failing in a transaction (e.g. on Postgres) would abort the caller's transaction
+	if execution.QueryType != enums.SELECT || execution.Err != nil || execution.InTx || timeout < 0 ||
+		!settings.acquireSlowQueryExplain() {
+		logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+		return
+	}
+
+	go func() {
+		defer settings.releaseSlowQueryExplain()
+
+		plan, err := s.getManager().explainQuery(execution, dialect, timeout)
+		if err != nil {
+			attrs = append(attrs, slog.String("explain_error", err.Error()))
+		} else {
+			attrs = append(attrs, slog.Any("plan", plan))
+		}
+		logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+	}()
+}
+
+// explainQuery runs the explain statement of the execution outside the interceptor chain on the database that
+// ran it and returns the plan rows, the Postgres JSON plan is returned as a json.RawMessage
+func (m *DBM) explainQuery(execution *QueryExecution, dialect types.Dialect, timeout time.Duration) (any, error) {
+	ctx := context.Background()
+	if execution.Context != nil {
+		// explain slow queries that ran out of time as well
+		ctx = context.WithoutCancel(execution.Context)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	query := dialects.GetDialect(dialect).CompileExplain(execution.Sql)
+
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	switch {
+	case execution.db != nil:
+		rows, err = execution.db.QueryContext(ctx, query, execution.Bindings...)
+	default:
+		// served without a round trip (e.g. an interceptor), explained on the primary
+		db, connErr := m.ConnectionDB(execution.Connection)
+		if connErr != nil {
+			return nil, connErr
+		}
+		rows, err = db.QueryContext(ctx, query, execution.Bindings...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scanner, err := newRowScanner(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []map[string]any
+	for rows.Next() {
+		row, err := scanner.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if dialect == types.DialectPostgres && len(plan) == 1 && len(scanner.columns) == 1 {
+		if document, ok := plan[0][scanner.columns[0]].(string); ok && json.Valid([]byte(document)) {
+			return json.RawMessage(document), nil
+		}
+	}
+
+	return plan, nil
+}
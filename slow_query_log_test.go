@@ -0,0 +1,225 @@
+package xqb_test
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/iMohamedSheta/xqb/shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// lockedBuffer is a bytes.Buffer safe for the background slow query records
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// slowQueryRecords returns settings logging every query as slow and the decoded log records,
+// it waits for the expected number of records written in the background
+func slowQueryRecords(t *testing.T) (*xqb.QueryBuilderSettings, func(expected int) []map[string]any) {
+	t.Helper()
+
+	buf := &lockedBuffer{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.LogSlowQueries(time.Nanosecond, slog.New(slog.NewJSONHandler(buf, nil)))
+
+	return settings, func(expected int) []map[string]any {
+		assert.Eventually(t, func() bool {
+			return strings.Count(buf.String(), "\n") >= expected
+		}, time.Second, time.Millisecond)
+
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			assert.NoError(t, json.Unmarshal([]byte(line), &record))
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func Test_SlowQueryLog_MySqlExplain(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		if strings.HasPrefix(query, "EXPLAIN ") {
+			return &fakeResult{
+				columns: []string{"table", "type", "rows"},
+				rows:    [][]driver.Value{{[]byte("users"), []byte("ALL"), int64(1000)}},
+			}, nil
+		}
+		return usersRows(2), nil
+	})
+	settings, records := slowQueryRecords(t)
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("name", "=", "bob").Get()
+
+	assert.NoError(t, err)
+
+	// the explain runs in the background
+	logged := records(1)
+	assert.Equal(t, []string{
+		"SELECT * FROM `users` WHERE `name` = ?",
+		"EXPLAIN SELECT * FROM `users` WHERE `name` = ?",
+	}, fdb.Statements())
+	assert.Len(t, logged, 1)
+	assert.Equal(t, "WARN", logged[0]["level"])
+	assert.Equal(t, "slow query", logged[0]["msg"])
+	assert.Equal(t, "SELECT * FROM `users` WHERE `name` = 'bob'", logged[0]["sql"])
+	assert.Equal(t, "users", logged[0]["table"])
+	assert.EqualValues(t, 2, logged[0]["rows"])
+	assert.Contains(t, logged[0], "duration")
+	assert.Equal(t, []any{map[string]any{"table": "users", "type": "ALL", "rows": float64(1000)}}, logged[0]["plan"])
+}
+
+func Test_SlowQueryLog_PostgresJsonPlan(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, func(query string, args []any) (*fakeResult, error) {
+		if strings.HasPrefix(query, "EXPLAIN (FORMAT JSON) ") {
+			return &fakeResult{
+				columns: []string{"QUERY PLAN"},
+				rows:    [][]driver.Value{{[]byte(`[{"Plan": {"Node Type": "Seq Scan"}}]`)}},
+			}, nil
+		}
+		return usersRows(1), nil
+	})
+	settings, records := slowQueryRecords(t)
+
+	_, err := xqb.Table("users").SetDialect(types.DialectPostgres).Connection(fdb.name).WithSettings(settings).Where("id", "=", 1).Get()
+
+	assert.NoError(t, err)
+
+	logged := records(1)
+	assert.Equal(t, `EXPLAIN (FORMAT JSON) SELECT * FROM "users" WHERE "id" = $1`, fdb.Statements()[1])
+	assert.Len(t, logged, 1)
+	assert.Equal(t, `SELECT * FROM "users" WHERE "id" = 1`, logged[0]["sql"])
+	assert.Equal(t, []any{map[string]any{"Plan": map[string]any{"Node Type": "Seq Scan"}}}, logged[0]["plan"])
+}
+
+func Test_SlowQueryLog_ExplainsOnTheServingDatabase(t *testing.T) {
+	primary := newFakeConnection(t, xqb.DialectMySql, nil)
+	replicas := newFakeReplicas(t, primary, 1, nil)
+	settings, records := slowQueryRecords(t)
+
+	_, err := xqb.Table("users").Connection(primary.name).WithSettings(settings).Get()
+	assert.NoError(t, err)
+
+	logged := records(1)
+	assert.Contains(t, logged[0], "plan")
+	assert.Equal(t, []string{"SELECT * FROM `users`", "EXPLAIN SELECT * FROM `users`"}, replicas[0].Statements())
+	assert.Empty(t, primary.Statements())
+}
+
+func Test_SlowQueryLog_TransactionsAreNotExplained(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	settings, records := slowQueryRecords(t)
+
+	err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+		_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).WithTx(tx).Get()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SELECT * FROM `users`"}, fdb.TxStatements())
+
+	logged := records(1)
+	assert.Equal(t, "SELECT * FROM `users`", logged[0]["sql"])
+	assert.NotContains(t, logged[0], "plan")
+}
+
+func Test_SlowQueryLog_NegativeExplainTimeoutDisablesExplain(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	settings, records := slowQueryRecords(t)
+	settings.SetSlowQueryExplainTimeout(-1)
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Get()
+
+	assert.NoError(t, err)
+	assert.NotContains(t, records(1)[0], "plan")
+	assert.Equal(t, []string{"SELECT * FROM `users`"}, fdb.Statements())
+}
+
+func Test_SlowQueryLog_BoundsTheRunningExplains(t *testing.T) {
+	release := make(chan struct{})
+	var explains atomic.Int32
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		if strings.HasPrefix(query, "EXPLAIN ") {
+			explains.Add(1)
+			<-release
+		}
+		return usersRows(1), nil
+	})
+	settings, records := slowQueryRecords(t)
+
+	for range 10 {
+		_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Get()
+		assert.NoError(t, err)
+	}
+
+	// the queries over the limit are logged right away without a plan
+	logged := records(6)
+	assert.Len(t, logged, 6)
+	for _, record := range logged {
+		assert.NotContains(t, record, "plan")
+	}
+
+	close(release)
+	assert.Len(t, records(10), 10)
+	assert.EqualValues(t, 4, explains.Load())
+}
+
+func Test_SlowQueryLog_WritesAreNotExplained(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+	settings, records := slowQueryRecords(t)
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("id", "=", 1).Delete()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DELETE FROM `users` WHERE `id` = ?"}, fdb.Statements())
+
+	logged := records(1)
+	assert.Len(t, logged, 1)
+	assert.Equal(t, "DELETE FROM `users` WHERE `id` = 1", logged[0]["sql"])
+	assert.NotContains(t, logged[0], "plan")
+}
+
+func Test_SlowQueryLog_BelowThreshold(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+
+	var buf bytes.Buffer
+	settings := xqb.NewQueryBuilderSettings()
+	settings.LogSlowQueries(time.Hour, slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Get()
+
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+	assert.Len(t, fdb.Statements(), 1)
+}
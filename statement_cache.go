@@ -0,0 +1,202 @@
+package xqb
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// StatementCacheStats are the counters of the prepared statement cache of a connection
+type StatementCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int // statements currently prepared
+	Capacity  int
+}
+
+// statementCache is a bounded LRU of the prepared statements of a connection keyed by database and sql,
+// the databases are the primary and the replicas of the connection
+type statementCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[statementKey]*list.Element
+	order    *list.List // most recently used first
+	stats    StatementCacheStats
+	closed   bool
+}
+
+type statementKey struct {
+	db  *sql.DB
+	sql string
+}
+
+type statementEntry struct {
+	key     statementKey
+	stmt    *sql.Stmt
+	refs    int  // executions using the statement
+	evicted bool // closed once the last execution releases it
+}
+
+func newStatementCache(capacity int) *statementCache {
+	return &statementCache{
+		capacity: capacity,
+		entries:  make(map[statementKey]*list.Element),
+		order:    list.New(),
+		stats:    StatementCacheStats{Capacity: capacity},
+	}
+}
+
+// acquire returns the prepared statement of the sql on the database, preparing it on a miss.
+// The entry must be released once the statement was executed.
+func (c *statementCache) acquire(ctx context.Context, db *sql.DB, query string) (*statementEntry, error) {
+	key := statementKey{db: db, sql: query}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errors.New("sql: statement cache is closed")
+	}
+	if element, ok := c.entries[key]; ok {
+		c.stats.Hits++
+		c.order.MoveToFront(element)
+		entry := element.Value.(*statementEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// prepare outside the lock so a slow prepare doesn't block the other statements
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		_ = stmt.Close()
+		return nil, errors.New("sql: statement cache is closed")
+	}
+	// prepared concurrently by another execution
+	if element, ok := c.entries[key]; ok {
+		_ = stmt.Close()
+		entry := element.Value.(*statementEntry)
+		entry.refs++
+		return entry, nil
+	}
+
+	entry := &statementEntry{key: key, stmt: stmt, refs: 1}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		c.evict(c.order.Back())
+	}
+	c.stats.Size = c.order.Len()
+
+	return entry, nil
+}
+
+// release ends an execution of the statement, an evicted statement is closed by its last execution
+func (c *statementCache) release(entry *statementEntry) {
+	c.mu.Lock()
+	entry.refs--
+	closeStmt := entry.evicted && entry.refs == 0
+	c.mu.Unlock()
+
+	if closeStmt {
+		_ = entry.stmt.Close()
+	}
+}
+
+// evict removes the element from the cache, its statement is closed when no execution uses it. Requires c.mu.
+func (c *statementCache) evict(element *list.Element) {
+	entry := c.order.Remove(element).(*statementEntry)
+	delete(c.entries, entry.key)
+	c.stats.Evictions++
+	c.stats.Size = c.order.Len()
+
+	entry.evicted = true
+	if entry.refs == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+// close closes the statements and disables the cache
+func (c *statementCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for c.order.Len() > 0 {
+		c.evict(c.order.Back())
+	}
+}
+
+// Stats returns the counters of the cache
+func (c *statementCache) Stats() StatementCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// execute runs the event with the cached statement of db, inside its transaction with tx.StmtContext
+func (c *statementCache) execute(ctx context.Context, db *sql.DB, event *QueryEvent) (Result, error) {
+	entry, err := c.acquire(ctx, db, event.Sql)
+	if err != nil {
+		return Result{}, err
+	}
+	// the rows keep the statement open until they are closed, see database/sql
+	defer c.release(entry)
+
+	stmt := entry.stmt
+	if event.Tx != nil {
+		// closed by database/sql when the transaction ends
+		stmt = event.Tx.StmtContext(ctx, stmt)
+	}
+
+	var result Result
+	if event.Kind == QueryKindExecute {
+		result.Exec, err = stmt.ExecContext(ctx, event.Bindings...)
+	} else {
+		result.Rows, err = stmt.QueryContext(ctx, event.Bindings...)
+	}
+	return result, err
+}
+
+// statements returns the prepared statement cache of the connection, nil when StatementCacheSize is 0
+func (c *Connection) statements() *statementCache {
+	if c.StatementCacheSize <= 0 {
+		return nil
+	}
+
+	c.stmtCacheOnce.Do(func() {
+		c.stmtCache = newStatementCache(c.StatementCacheSize)
+	})
+	return c.stmtCache
+}
+
+// closeStatements closes the cached prepared statements of the connection
+func (c *Connection) closeStatements() {
+	if cache := c.statements(); cache != nil {
+		cache.close()
+	}
+}
+
+// StatementCacheStats returns the hits, misses and evictions of the prepared statement cache of the connection
+func (m *DBM) StatementCacheStats(name string) (StatementCacheStats, error) {
+	conn, err := m.Connection(name)
+	if err != nil {
+		return StatementCacheStats{}, err
+	}
+
+	cache := conn.statements()
+	if cache == nil {
+		return StatementCacheStats{}, nil
+	}
+	return cache.Stats(), nil
+}
@@ -0,0 +1,105 @@
+package xqb_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeCachedConnection registers a fake connection with a prepared statement cache of the given size
+func newFakeCachedConnection(t *testing.T, size int) *fakeDB {
+	t.Helper()
+
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(1), nil
+	})
+	conn, err := xqb.GetConnection(fdb.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.StatementCacheSize = size
+
+	return fdb
+}
+
+func Test_StatementCache_ReusesStatements(t *testing.T) {
+	fdb := newFakeCachedConnection(t, 10)
+
+	for i := range 3 {
+		_, err := xqb.Table("users").Connection(fdb.name).Where("id", "=", i).Get()
+		assert.NoError(t, err)
+	}
+	_, err := xqb.Sql("DELETE FROM users WHERE id = ?", 1).Connection(fdb.name).Execute()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"SELECT * FROM `users` WHERE `id` = ?", "DELETE FROM users WHERE id = ?"}, fdb.Prepared())
+	assert.Len(t, fdb.Statements(), 4)
+
+	stats, err := xqb.DBManager().StatementCacheStats(fdb.name)
+	assert.NoError(t, err)
+	assert.Equal(t, xqb.StatementCacheStats{Hits: 2, Misses: 2, Size: 2, Capacity: 10}, stats)
+}
+
+func Test_StatementCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	fdb := newFakeCachedConnection(t, 2)
+
+	for _, query := range []string{"SELECT 1", "SELECT 2", "SELECT 1", "SELECT 3", "SELECT 2"} {
+		rows, err := xqb.Sql(query).Connection(fdb.name).Query()
+		assert.NoError(t, err)
+		assert.NoError(t, rows.Close())
+	}
+
+	// SELECT 2 was evicted by SELECT 3 then prepared again
+	assert.Equal(t, []string{"SELECT 1", "SELECT 2", "SELECT 3", "SELECT 2"}, fdb.Prepared())
+	assert.Equal(t, 2, fdb.ClosedStatements())
+
+	stats, _ := xqb.DBManager().StatementCacheStats(fdb.name)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+	assert.Equal(t, uint64(2), stats.Evictions)
+	assert.Equal(t, 2, stats.Size)
+}
+
+func Test_StatementCache_Transaction(t *testing.T) {
+	fdb := newFakeCachedConnection(t, 10)
+
+	for range 2 {
+		err := xqb.TransactionOn(fdb.name, func(tx *sql.Tx) error {
+			_, err := xqb.Table("users").Connection(fdb.name).WithTx(tx).Where("id", "=", 1).Delete()
+			return err
+		})
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"DELETE FROM `users` WHERE `id` = ?", "DELETE FROM `users` WHERE `id` = ?"}, fdb.TxStatements())
+
+	stats, _ := xqb.DBManager().StatementCacheStats(fdb.name)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Hits)
+}
+
+func Test_StatementCache_ClosedWithConnection(t *testing.T) {
+	fdb := newFakeCachedConnection(t, 10)
+
+	for _, query := range []string{"SELECT 1", "SELECT 2"} {
+		var id int
+		assert.NoError(t, xqb.Sql(query).Connection(fdb.name).QueryRow(&id, new(string)))
+	}
+
+	assert.NoError(t, xqb.Close(fdb.name))
+	assert.Equal(t, 2, fdb.ClosedStatements())
+}
+
+func Test_StatementCache_Disabled(t *testing.T) {
+	fdb := newFakeCachedConnection(t, 0)
+
+	_, err := xqb.Table("users").Connection(fdb.name).Get()
+
+	assert.NoError(t, err)
+	assert.Empty(t, fdb.Prepared())
+	stats, err := xqb.DBManager().StatementCacheStats(fdb.name)
+	assert.NoError(t, err)
+	assert.Zero(t, stats)
+}
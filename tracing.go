@@ -0,0 +1,123 @@
+package xqb
+
+import (
+	"context"
+
+	"github.com/iMohamedSheta/xqb/shared/types"
+)
+
+// Tracer starts the spans of the database round trips and transactions, implement it
+// with OpenTelemetry or any other tracer to trace the queries without xqb depending on it.
+//
+// Example:
+//
+//	xqb.DefaultSettings().SetTracer(otelTracer{tracer: otel.Tracer("xqb")})
+type Tracer interface {
+	// StartSpan starts a span, the returned context carries it and is passed to the driver
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	SetAttributes(attributes map[string]any)
+	RecordError(err error)
+	End()
+}
+
+// SetTracer sets the tracer called around every database round trip,
+// the tracer of the manager settings (see DBM.SetSettings) also traces its transactions.
+func (s *QueryBuilderSettings) SetTracer(tracer Tracer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tracer = tracer
+}
+
+// GetTracer returns the tracer of the settings
+func (s *QueryBuilderSettings) GetTracer() Tracer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tracer
+}
+
+// noopSpan is used when no tracer is set
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(map[string]any) {}
+func (noopSpan) RecordError(error)            {}
+func (noopSpan) End()                         {}
+
+// startSpan starts a span with the tracer or returns a no-op span when the tracer is nil
+func startSpan(ctx context.Context, tracer Tracer, name string, attributes map[string]any) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	ctx, span := tracer.StartSpan(ctx, name)
+	if span == nil {
+		return ctx, noopSpan{}
+	}
+	span.SetAttributes(attributes)
+
+	return ctx, span
+}
+
+// dbSystem returns the db.system attribute of the connection dialect
+func (m *DBM) dbSystem(connection string) string {
+	dialect, err := m.GetDialect(connection)
+	if err != nil {
+		return "other_sql"
+	}
+
+	switch dialect.MappedDialect() {
+	case types.DialectPostgres:
+		return "postgresql"
+	case types.DialectMySql:
+		return "mysql"
+	default:
+		return "other_sql"
+	}
+}
+
+// startQuerySpan starts the span of the database round trip of the event,
+// it's named after the query type and table ("SELECT users") following the database semantic conventions.
+func (s *SqlQuery) startQuerySpan(ctx context.Context, event *QueryEvent) (context.Context, Span) {
+	operation := s.queryType.String()
+	if operation == "" {
+		operation = inferQueryType(event.Sql).String()
+	}
+
+	name := operation
+	if name == "" {
+		name = string(event.Kind)
+	}
+	if s.table != "" {
+		name += " " + s.table
+	}
+
+	attributes := map[string]any{
+		"db.system":      event.getManager().dbSystem(event.Connection),
+		"db.statement":   event.Sql,
+		"xqb.connection": event.Connection,
+	}
+	if operation != "" {
+		attributes["db.operation"] = operation
+	}
+	if s.table != "" {
+		attributes["db.sql.table"] = s.table
+	}
+
+	return startSpan(ctx, s.getSettings().GetTracer(), name, attributes)
+}
+
+// endQuerySpan ends the span of a database round trip with its error, the statements
+// run with Execute also record the affected rows
+func endQuerySpan(span Span, result Result, err error) {
+	if err != nil {
+		span.RecordError(err)
+	} else if result.Exec != nil {
+		if affected, rowsErr := result.Exec.RowsAffected(); rowsErr == nil {
+			span.SetAttributes(map[string]any{"db.rows": affected})
+		}
+	}
+	span.End()
+}
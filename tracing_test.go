@@ -0,0 +1,213 @@
+package xqb_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/iMohamedSheta/xqb/shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type spanKey struct{}
+
+// recordingTracer keeps the started spans in order
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	name       string
+	parent     *recordingSpan
+	attributes map[string]any
+	errors     []error
+	ended      bool
+}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, xqb.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordingSpan)
+	span := &recordingSpan{name: name, parent: parent, attributes: map[string]any{}}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *recordingSpan) SetAttributes(attributes map[string]any) {
+	for key, value := range attributes {
+		s.attributes[key] = value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) { s.errors = append(s.errors, err) }
+func (s *recordingSpan) End()                  { s.ended = true }
+
+func Test_Tracer_QuerySpan(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return usersRows(2), nil
+	})
+
+	tracer := &recordingTracer{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.SetTracer(tracer)
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("id", ">", 1).Get()
+
+	assert.NoError(t, err)
+	assert.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "SELECT users", span.name)
+	assert.True(t, span.ended)
+	assert.Equal(t, map[string]any{
+		"db.system":      "mysql",
+		"db.statement":   "SELECT * FROM `users` WHERE `id` > ?",
+		"db.operation":   "SELECT",
+		"db.sql.table":   "users",
+		"xqb.connection": fdb.name,
+	}, span.attributes)
+}
+
+func Test_Tracer_ExecuteSpanRecordsAffectedRows(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, func(query string, args []any) (*fakeResult, error) {
+		return &fakeResult{rowsAffected: 3}, nil
+	})
+
+	tracer := &recordingTracer{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.SetTracer(tracer)
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Where("active", "=", false).Delete()
+
+	assert.NoError(t, err)
+	assert.Len(t, tracer.spans, 1)
+	assert.Equal(t, "DELETE users", tracer.spans[0].name)
+	assert.Equal(t, int64(3), tracer.spans[0].attributes["db.rows"])
+	assert.True(t, tracer.spans[0].ended)
+}
+
+func Test_Tracer_SpanPerRoundTrip(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	tracer := &recordingTracer{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.SetTracer(tracer)
+	// retries the statement once
+	settings.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		if _, err := next(ctx, event); err != nil {
+			return xqb.Result{}, err
+		}
+		return next(ctx, event)
+	})
+
+	_, err := xqb.Sql("UPDATE users SET active = ?", true).Connection(fdb.name).WithSettings(settings).Execute()
+
+	assert.NoError(t, err)
+	assert.Len(t, fdb.Statements(), 2)
+	assert.Len(t, tracer.spans, 2)
+	assert.True(t, tracer.spans[0].ended)
+	assert.True(t, tracer.spans[1].ended)
+}
+
+func Test_Tracer_RecordsQueryError(t *testing.T) {
+	queryErr := errors.New("syntax error")
+	fdb := newFakeConnection(t, xqb.DialectPostgres, func(query string, args []any) (*fakeResult, error) {
+		return nil, queryErr
+	})
+
+	tracer := &recordingTracer{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.SetTracer(tracer)
+
+	_, err := xqb.Sql("UPDATE users SET name = $1", "bob").Connection(fdb.name).WithSettings(settings).Execute()
+
+	assert.ErrorIs(t, err, queryErr)
+	assert.Len(t, tracer.spans, 1)
+	assert.Equal(t, "UPDATE", tracer.spans[0].name)
+	assert.Equal(t, "postgresql", tracer.spans[0].attributes["db.system"])
+	assert.Equal(t, "UPDATE", tracer.spans[0].attributes["db.operation"])
+	assert.NotContains(t, tracer.spans[0].attributes, "db.sql.table")
+	assert.Len(t, tracer.spans[0].errors, 1)
+	assert.True(t, tracer.spans[0].ended)
+}
+
+func Test_Tracer_ShortCircuitedQueryHasNoSpan(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	tracer := &recordingTracer{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.SetTracer(tracer)
+	settings.Use(func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		return (&xqb.BufferedRows{Columns: []string{"id"}}).Result()
+	})
+
+	_, err := xqb.Table("users").Connection(fdb.name).WithSettings(settings).Get()
+
+	assert.NoError(t, err)
+	assert.Empty(t, tracer.spans)
+	assert.Empty(t, fdb.Statements())
+}
+
+func Test_Tracer_TransactionSpanIsTheParent(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectPostgres, nil)
+
+	tracer := &recordingTracer{}
+	xqb.DefaultSettings().SetTracer(tracer)
+	t.Cleanup(func() { xqb.DefaultSettings().SetTracer(nil) })
+
+	err := xqb.TransactionCtxOn(context.Background(), fdb.name, &xqb.TxOptions{Isolation: sql.LevelSerializable}, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := xqb.Table("users").SetDialect(types.DialectPostgres).Connection(fdb.name).WithContext(ctx).Where("id", "=", 1).Delete()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, tracer.spans, 2)
+	transaction, query := tracer.spans[0], tracer.spans[1]
+	assert.Equal(t, "TRANSACTION", transaction.name)
+	assert.Equal(t, "Serializable", transaction.attributes["xqb.transaction.isolation"])
+	assert.Equal(t, true, transaction.attributes["xqb.transaction.committed"])
+	assert.True(t, transaction.ended)
+	assert.Equal(t, "DELETE users", query.name)
+	assert.Same(t, transaction, query.parent)
+}
+
+func Test_Tracer_RolledBackTransaction(t *testing.T) {
+	fdb := newFakeConnection(t, xqb.DialectMySql, nil)
+
+	tracer := &recordingTracer{}
+	xqb.DefaultSettings().SetTracer(tracer)
+	t.Cleanup(func() { xqb.DefaultSettings().SetTracer(nil) })
+
+	tx, err := xqb.BeginTxHandleOn(fdb.name)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+
+	assert.Len(t, tracer.spans, 1)
+	assert.Equal(t, false, tracer.spans[0].attributes["xqb.transaction.committed"])
+	assert.True(t, tracer.spans[0].ended)
+}
+
+func Test_Tracer_ManagerSettings(t *testing.T) {
+	mgr, _ := newFakeManager(t, xqb.DialectMySql, nil)
+
+	tracer := &recordingTracer{}
+	settings := xqb.NewQueryBuilderSettings()
+	settings.SetTracer(tracer)
+	mgr.SetSettings(settings)
+
+	err := mgr.Transaction(func(tx *sql.Tx) error {
+		_, err := mgr.Table("users").WithTx(tx).Where("id", "=", 1).Delete()
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, tracer.spans, 2)
+	assert.Equal(t, "TRANSACTION", tracer.spans[0].name)
+	assert.Equal(t, "DELETE users", tracer.spans[1].name)
+	assert.Same(t, xqb.DefaultSettings(), xqb.NewManager().GetSettings())
+}
@@ -0,0 +1,243 @@
+package xqbtest
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// statementKind is the driver call an expectation answers
+type statementKind string
+
+const (
+	kindBegin    statementKind = "BEGIN"
+	kindCommit   statementKind = "COMMIT"
+	kindRollback statementKind = "ROLLBACK"
+	kindQuery    statementKind = "query"
+	kindExec     statementKind = "exec"
+)
+
+// Expectation is a statement the code under test must run and the response of the fake driver
+type Expectation struct {
+	kind    statementKind
+	sql     string
+	pattern *regexp.Regexp
+	args    []any
+	hasArgs bool
+	rows    *Rows
+	result  driver.Result
+	err     error
+	times   int
+	calls   int
+}
+
+// WithArgs sets the expected bindings, an Argument matches a binding with custom logic (see AnyArg)
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+// WillReturnRows sets the rows returned by the query
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult sets the result of the statement
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+	return e
+}
+
+// WillReturnError makes the statement fail with err
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times sets how many times the statement is expected, once by default
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// fulfilled reports whether the statement ran as many times as expected
+func (e *Expectation) fulfilled() bool {
+	return e.calls >= e.times
+}
+
+// match returns why the statement doesn't match the expectation, an empty string when it matches
+func (e *Expectation) match(kind statementKind, query string, args []driver.NamedValue) string {
+	if e.kind != kind {
+		return fmt.Sprintf("expected %s, got %s", e, kind)
+	}
+	if kind != kindQuery && kind != kindExec {
+		return ""
+	}
+
+	if e.pattern != nil {
+		if !e.pattern.MatchString(query) {
+			return fmt.Sprintf("sql %q doesn't match the pattern %q", query, e.pattern)
+		}
+	} else if normalizeSql(query) != normalizeSql(e.sql) {
+		return fmt.Sprintf("sql %q doesn't match the expected %q", query, e.sql)
+	}
+
+	if !e.hasArgs {
+		return ""
+	}
+	if len(args) != len(e.args) {
+		return fmt.Sprintf("expected %d binding(s) %v, got %d %v", len(e.args), e.args, len(args), namedValues(args))
+	}
+	for i, expected := range e.args {
+		if !matchArg(expected, args[i].Value) {
+			return fmt.Sprintf("binding %d: expected %v (%T), got %v (%T)", i, expected, expected, args[i].Value, args[i].Value)
+		}
+	}
+
+	return ""
+}
+
+func (e *Expectation) String() string {
+	switch {
+	case e.pattern != nil:
+		return fmt.Sprintf("%s matching %q", e.kind, e.pattern)
+	case e.sql != "":
+		return fmt.Sprintf("%s %q", e.kind, e.sql)
+	default:
+		return string(e.kind)
+	}
+}
+
+var placeholderRegex = regexp.MustCompile(`\$\d+`)
+
+// normalizeSql collapses the whitespace and rewrites the Postgres placeholders ($1, $2...) to ?,
+// an expected statement written with either style matches both dialects.
+func normalizeSql(query string) string {
+	return placeholderRegex.ReplaceAllString(strings.Join(strings.Fields(query), " "), "?")
+}
+
+// Argument matches a binding of a statement
+type Argument interface {
+	Match(value driver.Value) bool
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(driver.Value) bool { return true }
+
+func (anyArg) String() string { return "<any>" }
+
+// AnyArg returns an Argument matching any binding
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+// matchArg compares the expected binding with the binding converted by database/sql
+func matchArg(expected any, actual driver.Value) bool {
+	if arg, ok := expected.(Argument); ok {
+		return arg.Match(actual)
+	}
+
+	converted, err := driver.DefaultParameterConverter.ConvertValue(expected)
+	if err != nil {
+		return false
+	}
+	if expectedTime, ok := converted.(time.Time); ok {
+		actualTime, ok := actual.(time.Time)
+		return ok && expectedTime.Equal(actualTime)
+	}
+	if expectedBytes, ok := converted.([]byte); ok {
+		if actualString, ok := actual.(string); ok {
+			return string(expectedBytes) == actualString
+		}
+	}
+	if expectedString, ok := converted.(string); ok {
+		if actualBytes, ok := actual.([]byte); ok {
+			return expectedString == string(actualBytes)
+		}
+	}
+
+	return reflect.DeepEqual(converted, actual)
+}
+
+func namedValues(args []driver.NamedValue) []any {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// Rows is the result set returned by an expected query
+type Rows struct {
+	columns []string
+	values  [][]any
+}
+
+// NewRows creates an empty result set with the columns
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow appends a row, the values are converted like the bindings of database/sql
+func (r *Rows) AddRow(values ...any) *Rows {
+	r.values = append(r.values, values)
+	return r
+}
+
+// driverRows returns a new reader of the result set, the same rows can answer several queries
+type driverRows struct {
+	rows *Rows
+	pos  int
+}
+
+func (r *driverRows) Columns() []string {
+	return r.rows.columns
+}
+
+func (r *driverRows) Close() error {
+	return nil
+}
+
+func (r *driverRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows.values) {
+		return io.EOF
+	}
+
+	row := r.rows.values[r.pos]
+	r.pos++
+
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		value, err := driver.DefaultParameterConverter.ConvertValue(row[i])
+		if err != nil {
+			return fmt.Errorf("xqbtest: column %q: %w", r.rows.columns[i], err)
+		}
+		dest[i] = value
+	}
+
+	return nil
+}
+
+// result is the driver.Result of an expected statement
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
@@ -0,0 +1,360 @@
+// Package xqbtest provides test doubles for code using xqb without a database.
+//
+// Example:
+//
+//	func TestActiveUsers(t *testing.T) {
+//		mock := xqbtest.New(t, xqb.DialectMySql)
+//		mock.ExpectQuery("SELECT * FROM `users` WHERE `active` = ?").
+//			WithArgs(true).
+//			WillReturnRows(xqbtest.NewRows("id", "name").AddRow(1, "Ada"))
+//
+//		users, err := mock.Manager().Table("users").Where("active", "=", true).Get()
+//		...
+//	}
+package xqbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/iMohamedSheta/xqb"
+)
+
+// ErrUnexpectedStatement is returned by the fake driver for a statement no expectation matches
+var ErrUnexpectedStatement = errors.New("xqbtest: unexpected statement")
+
+// TB is the part of testing.TB used by the mock
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// Mock is a fake xqb connection answering the statements with the declared expectations.
+// Unexpected statements fail the test when they run, unmet expectations when the test ends.
+type Mock struct {
+	t       TB
+	name    string
+	dialect xqb.Dialect
+	manager *xqb.DBM
+	db      *sql.DB
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	ordered      bool
+	permissive   bool // accept every statement, used by the replayed connections
+}
+
+// Option configures the connection registered by New
+type Option func(m *Mock)
+
+// WithName registers the connection under name, "default" by default
+func WithName(name string) Option {
+	return func(m *Mock) {
+		m.name = name
+	}
+}
+
+// WithManager registers the connection on the manager, by default on a new manager as its default connection
+// (see Mock.Manager). Pass xqb.DBManager() to serve the global helpers, the replaced connection is restored
+// when the test ends.
+func WithManager(manager *xqb.DBM) Option {
+	return func(m *Mock) {
+		m.manager = manager
+	}
+}
+
+// New registers a fake connection with the dialect for the duration of the test,
+// the expectations are matched in the order they're declared (see MatchExpectationsInOrder).
+func New(t TB, dialect xqb.Dialect, options ...Option) *Mock {
+	t.Helper()
+
+	m := &Mock{t: t, name: "default", dialect: dialect, ordered: true}
+	for _, option := range options {
+		option(m)
+	}
+	owned := m.manager == nil
+	if owned {
+		m.manager = xqb.NewManager()
+	}
+	m.db = sql.OpenDB(connector{mock: m})
+
+	previous, _ := m.manager.Connection(m.name)
+	if err := m.manager.SetConnection(&xqb.Connection{Name: m.name, DB: m.db, Dialect: dialect}); err != nil {
+		t.Errorf("xqbtest: failed to register the connection %q: %v", m.name, err)
+	}
+	if owned {
+		_ = m.manager.SetDefaultConnection(m.name)
+	}
+
+	t.Cleanup(func() {
+		if previous != nil {
+			_ = m.manager.SetConnection(previous)
+		} else {
+			_ = m.manager.CloseConnection(m.name)
+		}
+		_ = m.db.Close()
+
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+
+	return m
+}
+
+// Name returns the name of the connection
+func (m *Mock) Name() string {
+	return m.name
+}
+
+// Dialect returns the dialect of the connection
+func (m *Mock) Dialect() xqb.Dialect {
+	return m.dialect
+}
+
+// Manager returns the manager the connection is registered on
+func (m *Mock) Manager() *xqb.DBM {
+	return m.manager
+}
+
+// DB returns the fake database of the connection
+func (m *Mock) DB() *sql.DB {
+	return m.db
+}
+
+// MatchExpectationsInOrder sets whether the statements must run in the order of the expectations
+func (m *Mock) MatchExpectationsInOrder(ordered bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ordered = ordered
+}
+
+// ExpectBegin expects a transaction to begin
+func (m *Mock) ExpectBegin() *Expectation {
+	return m.expect(&Expectation{kind: kindBegin})
+}
+
+// ExpectCommit expects a transaction to commit
+func (m *Mock) ExpectCommit() *Expectation {
+	return m.expect(&Expectation{kind: kindCommit})
+}
+
+// ExpectRollback expects a transaction to roll back
+func (m *Mock) ExpectRollback() *Expectation {
+	return m.expect(&Expectation{kind: kindRollback})
+}
+
+// ExpectQuery expects a query returning rows with the exact sql, the whitespace is collapsed and the
+// placeholders may be written ? or $n for both dialects.
+func (m *Mock) ExpectQuery(sql string) *Expectation {
+	return m.expect(&Expectation{kind: kindQuery, sql: sql})
+}
+
+// ExpectQueryMatch expects a query returning rows with a sql matching the regular expression
+func (m *Mock) ExpectQueryMatch(pattern string) *Expectation {
+	return m.expect(&Expectation{kind: kindQuery, pattern: regexp.MustCompile(pattern)})
+}
+
+// ExpectExec expects a statement executed without rows with the exact sql (see ExpectQuery)
+func (m *Mock) ExpectExec(sql string) *Expectation {
+	return m.expect(&Expectation{kind: kindExec, sql: sql})
+}
+
+// ExpectExecMatch expects a statement executed without rows with a sql matching the regular expression
+func (m *Mock) ExpectExecMatch(pattern string) *Expectation {
+	return m.expect(&Expectation{kind: kindExec, pattern: regexp.MustCompile(pattern)})
+}
+
+func (m *Mock) expect(e *Expectation) *Expectation {
+	e.times = 1
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// ExpectationsWereMet returns an error listing the expectations that didn't run
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unmet []string
+	for _, e := range m.expectations {
+		if !e.fulfilled() {
+			unmet = append(unmet, fmt.Sprintf("%s (ran %d of %d times)", e, e.calls, e.times))
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("xqbtest: unmet expectations on %q:\n\t%s", m.name, strings.Join(unmet, "\n\t"))
+	}
+	return nil
+}
+
+// next returns the expectation answering the statement, the test fails when there is none
+func (m *Mock) next(kind statementKind, query string, args []driver.NamedValue) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.permissive {
+		return &Expectation{kind: kind}, nil
+	}
+
+	var reasons []string
+	for _, e := range m.expectations {
+		if e.fulfilled() {
+			continue
+		}
+		reason := e.match(kind, query, args)
+		if reason == "" {
+			e.calls++
+			return e, e.err
+		}
+		reasons = append(reasons, reason)
+		if m.ordered {
+			break
+		}
+	}
+
+	statement := string(kind)
+	if query != "" {
+		statement = fmt.Sprintf("%s %q %v", kind, query, namedValues(args))
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "no expectation left")
+	}
+	err := fmt.Errorf("%w on %q: %s: %s", ErrUnexpectedStatement, m.name, statement, strings.Join(reasons, "; "))
+	m.t.Errorf("%v", err)
+
+	return nil, err
+}
+
+// connector opens the connections of the fake database
+type connector struct {
+	mock *Mock
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{mock: c.mock}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return fakeDriver{mock: c.mock}
+}
+
+type fakeDriver struct {
+	mock *Mock
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &conn{mock: d.mock}, nil
+}
+
+// conn runs the statements through the expectations of the mock
+type conn struct {
+	mock *Mock
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.mock.next(kindBegin, "", nil); err != nil {
+		return nil, err
+	}
+	return &tx{mock: c.mock}, nil
+}
+
+func (c *conn) Ping(context.Context) error {
+	return nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.mock.next(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	rows := e.rows
+	if rows == nil {
+		rows = NewRows()
+	}
+	return &driverRows{rows: rows}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.mock.next(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return result{}, nil
+	}
+	return e.result, nil
+}
+
+// stmt defers to the connection so prepared statements match the same expectations
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamed(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamed(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+type tx struct {
+	mock *Mock
+}
+
+func (t *tx) Commit() error {
+	_, err := t.mock.next(kindCommit, "", nil)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	_, err := t.mock.next(kindRollback, "", nil)
+	return err
+}
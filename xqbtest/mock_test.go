@@ -0,0 +1,146 @@
+package xqbtest_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/iMohamedSheta/xqb/shared/types"
+	"github.com/iMohamedSheta/xqb/xqbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorderTB records the failures of the mock instead of failing the test
+type recorderTB struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recorderTB) Helper() {}
+
+func (r *recorderTB) Name() string {
+	return "Recorder"
+}
+
+func (r *recorderTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorderTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recorderTB) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func Test_Mock_QueryWithArgsAndRows(t *testing.T) {
+	mock := xqbtest.New(t, xqb.DialectMySql)
+	mock.ExpectQuery("SELECT * FROM `users` WHERE `active` = ? AND `age` > ?").
+		WithArgs(true, 18).
+		WillReturnRows(xqbtest.NewRows("id", "name").AddRow(1, "Ada").AddRow(2, "Linus"))
+
+	users, err := mock.Manager().Table("users").Where("active", "=", true).Where("age", ">", 18).Get()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"id": int64(1), "name": "Ada"},
+		{"id": int64(2), "name": "Linus"},
+	}, users)
+}
+
+func Test_Mock_PostgresPlaceholdersAndRegex(t *testing.T) {
+	mock := xqbtest.New(t, xqb.DialectPostgres)
+	mock.ExpectExec(`UPDATE "users" SET "name" = $1 WHERE "id" = $2`).WithArgs("Ada", xqbtest.AnyArg()).WillReturnResult(0, 1)
+	mock.ExpectQueryMatch(`^SELECT .* FROM "users"`).WillReturnRows(xqbtest.NewRows("id").AddRow(1))
+
+	affected, err := mock.Manager().Table("users").SetDialect(types.DialectPostgres).
+		Where("id", "=", 1).Update(map[string]any{"name": "Ada"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	users, err := mock.Manager().Table("users").SetDialect(types.DialectPostgres).Where("id", "=", 1).Get()
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func Test_Mock_Transactions(t *testing.T) {
+	mock := xqbtest.New(t, xqb.DialectMySql)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `users` WHERE `id` = ?").WithArgs(1).WillReturnResult(0, 1)
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `posts` WHERE `id` = ?").WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	mgr := mock.Manager()
+	err := mgr.Transaction(func(tx *sql.Tx) error {
+		_, err := mgr.Table("users").WithTx(tx).Where("id", "=", 1).Delete()
+		return err
+	})
+	assert.NoError(t, err)
+
+	err = mgr.TransactionCtx(context.Background(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := mgr.Table("posts").WithContext(ctx).Where("id", "=", 2).Delete()
+		return err
+	})
+	assert.ErrorContains(t, err, "boom")
+}
+
+func Test_Mock_GlobalManagerRestoresTheConnection(t *testing.T) {
+	// the connection replaced by the mock
+	outer := xqbtest.New(t, xqb.DialectMySql, xqbtest.WithManager(xqb.DBManager()))
+	previous, err := xqb.GetConnection("default")
+	assert.NoError(t, err)
+	assert.Same(t, outer.DB(), previous.DB)
+
+	rec := &recorderTB{}
+	mock := xqbtest.New(rec, xqb.DialectMySql, xqbtest.WithManager(xqb.DBManager()))
+	mock.ExpectQuery("SELECT COUNT(`id`) AS `count` FROM `users`").WillReturnRows(xqbtest.NewRows("count").AddRow(3))
+
+	count, err := xqb.Table("users").Count("id")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	rec.finish()
+	assert.Empty(t, rec.errors)
+
+	restored, err := xqb.GetConnection("default")
+	assert.NoError(t, err)
+	assert.Same(t, previous, restored)
+}
+
+func Test_Mock_FailsOnUnexpectedAndUnmetStatements(t *testing.T) {
+	rec := &recorderTB{}
+	mock := xqbtest.New(rec, xqb.DialectMySql, xqbtest.WithName("reports"))
+	mock.ExpectExec("DELETE FROM `users`")
+	mock.ExpectQuery("SELECT * FROM `posts`")
+
+	_, err := mock.Manager().Table("users").Connection("reports").Where("id", "=", 1).Get()
+	assert.ErrorIs(t, err, xqbtest.ErrUnexpectedStatement)
+	assert.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], "expected exec \"DELETE FROM `users`\", got query")
+
+	rec.finish()
+	assert.Len(t, rec.errors, 2)
+	assert.Contains(t, rec.errors[1], "unmet expectations")
+	assert.Contains(t, rec.errors[1], "SELECT * FROM `posts`")
+}
+
+func Test_Mock_UnorderedAndRepeatedExpectations(t *testing.T) {
+	mock := xqbtest.New(t, xqb.DialectMySql)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery("SELECT * FROM `posts`").Times(2)
+	mock.ExpectQuery("SELECT * FROM `users`")
+
+	mgr := mock.Manager()
+	for _, table := range []string{"users", "posts", "posts"} {
+		_, err := mgr.Table(table).Get()
+		assert.NoError(t, err)
+	}
+}
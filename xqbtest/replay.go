@@ -0,0 +1,597 @@
+package xqbtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/iMohamedSheta/xqb"
+)
+
+// ErrReplayDivergence is returned by the replay interceptor for a statement the fixture doesn't have next
+var ErrReplayDivergence = errors.New("xqbtest: replay diverged from the recorded queries")
+
+// Fixture holds the queries recorded by a Recorder, it's stored as JSON
+type Fixture struct {
+	Connections map[string]xqb.Dialect `json:"connections"`
+	Queries     []RecordedQuery        `json:"queries"`
+}
+
+// RecordedQuery is a statement with its result or error
+type RecordedQuery struct {
+	Kind         xqb.QueryKind `json:"kind"`
+	Connection   string        `json:"connection"`
+	Sql          string        `json:"sql"`
+	Bindings     []Value       `json:"bindings"`
+	Columns      []string      `json:"columns,omitempty"`
+	Rows         [][]Value     `json:"rows,omitempty"`
+	RowsAffected *int64        `json:"rows_affected,omitempty"`
+	LastInsertID *int64        `json:"last_insert_id,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	// ErrorSentinel is the message of the database/sql, context or xqb sentinel the error matched (sql.ErrNoRows...)
+	ErrorSentinel string           `json:"error_sentinel,omitempty"`
+	DBError       *RecordedDBError `json:"db_error,omitempty"` // set when the error is a *xqb.DBError
+}
+
+// RecordedDBError holds the fields of a *xqb.DBError, Kind is the message of its sentinel (xqb_deadlock...)
+type RecordedDBError struct {
+	Kind       string `json:"kind"`
+	Number     int    `json:"number,omitempty"`
+	SQLState   string `json:"sqlstate,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	Table      string `json:"table,omitempty"`
+	Column     string `json:"column,omitempty"`
+}
+
+// errorSentinels are the errors a recorded error is matched against so the replayed one matches them too
+var errorSentinels = []error{
+	sql.ErrNoRows,
+	sql.ErrTxDone,
+	sql.ErrConnDone,
+	driver.ErrBadConn,
+	context.Canceled,
+	context.DeadlineExceeded,
+	xqb.ErrUniqueViolation,
+	xqb.ErrForeignKeyViolation,
+	xqb.ErrNotNullViolation,
+	xqb.ErrCheckViolation,
+	xqb.ErrDeadlock,
+	xqb.ErrLockTimeout,
+	xqb.ErrSerialization,
+	xqb.ErrConnectionLost,
+	xqb.ErrNoConnection,
+	xqb.ErrQueryFailed,
+}
+
+// Value is a binding or a column value with its driver type (null, int64, float64, bool, string, bytes,
+// base64, time or other) so the replayed rows scan like the recorded ones.
+type Value struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// LoadFixture reads a JSON fixture
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("xqbtest: failed to read the fixture %s: %w", path, err)
+	}
+
+	fixture := &Fixture{}
+	if err := json.Unmarshal(data, fixture); err != nil {
+		return nil, fmt.Errorf("xqbtest: failed to decode the fixture %s: %w", path, err)
+	}
+	return fixture, nil
+}
+
+// Save writes the fixture as indented JSON, creating its directory
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("xqbtest: failed to encode the fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("xqbtest: failed to create the fixture directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Recorder is an interceptor capturing the statements, their results and errors into a Fixture
+type Recorder struct {
+	manager *xqb.DBM
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder creates a recorder, the dialects of the recorded connections are read from the manager
+func NewRecorder(manager *xqb.DBM) *Recorder {
+	return &Recorder{manager: manager, fixture: Fixture{Connections: make(map[string]xqb.Dialect)}}
+}
+
+// Interceptor returns the recording interceptor, register it last so it records the statements sent to the
+// database. The rows are read into memory and served from there.
+func (r *Recorder) Interceptor() xqb.Interceptor {
+	return func(ctx context.Context, event *xqb.QueryEvent, next xqb.QueryHandler) (xqb.Result, error) {
+		recorded := RecordedQuery{
+			Kind:       event.Kind,
+			Connection: event.Connection,
+			Sql:        event.Sql,
+			Bindings:   encodeBindings(event.Bindings),
+		}
+
+		result, err := next(ctx, event)
+		if err == nil && result.Rows != nil {
+			var rows *xqb.BufferedRows
+			rows, err = xqb.BufferRows(result.Rows)
+			if err == nil {
+				recorded.Columns = rows.Columns
+				recorded.Rows = make([][]Value, len(rows.Values))
+				for i, row := range rows.Values {
+					recorded.Rows[i] = encodeValues(row)
+				}
+				result, err = rows.Result()
+			}
+		}
+		if err == nil && result.Exec != nil {
+			if affected, affectedErr := result.Exec.RowsAffected(); affectedErr == nil {
+				recorded.RowsAffected = &affected
+			}
+			if id, idErr := result.Exec.LastInsertId(); idErr == nil {
+				recorded.LastInsertID = &id
+			}
+		}
+		if err != nil {
+			recordError(&recorded, err)
+		}
+
+		r.record(recorded)
+		return result, err
+	}
+}
+
+// recordError records the message of the error with its sentinel or its *xqb.DBError fields
+func recordError(recorded *RecordedQuery, err error) {
+	recorded.Error = err.Error()
+
+	var dbErr *xqb.DBError
+	if errors.As(err, &dbErr) {
+		recorded.DBError = &RecordedDBError{
+			Kind:       sentinelOf(dbErr.Kind),
+			Number:     dbErr.Number,
+			SQLState:   dbErr.SQLState,
+			Constraint: dbErr.Constraint,
+			Table:      dbErr.Table,
+			Column:     dbErr.Column,
+		}
+		return
+	}
+	recorded.ErrorSentinel = sentinelOf(err)
+}
+
+// sentinelOf returns the message of the first sentinel the error matches
+func sentinelOf(err error) string {
+	for _, sentinel := range errorSentinels {
+		if errors.Is(err, sentinel) {
+			return sentinel.Error()
+		}
+	}
+	return ""
+}
+
+// sentinelNamed returns the sentinel with the message, nil when it isn't known
+func sentinelNamed(message string) error {
+	for _, sentinel := range errorSentinels {
+		if sentinel.Error() == message {
+			return sentinel
+		}
+	}
+	return nil
+}
+
+// replayedError is a recorded error with its message, it matches the recorded sentinel
+type replayedError struct {
+	message  string
+	sentinel error
+}
+
+func (e *replayedError) Error() string { return e.message }
+func (e *replayedError) Unwrap() error { return e.sentinel }
+
+// err rebuilds the recorded error: a *xqb.DBError with its kind or an error matching the recorded sentinel
+func (q RecordedQuery) err() error {
+	if q.DBError != nil {
+		return &xqb.DBError{
+			Kind:       sentinelNamed(q.DBError.Kind),
+			Number:     q.DBError.Number,
+			SQLState:   q.DBError.SQLState,
+			Constraint: q.DBError.Constraint,
+			Table:      q.DBError.Table,
+			Column:     q.DBError.Column,
+			Err:        &replayedError{message: q.Error},
+		}
+	}
+	return &replayedError{message: q.Error, sentinel: sentinelNamed(q.ErrorSentinel)}
+}
+
+func (r *Recorder) record(recorded RecordedQuery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.fixture.Connections[recorded.Connection]; !ok {
+		dialect, _ := r.manager.GetDialect(recorded.Connection)
+		r.fixture.Connections[recorded.Connection] = dialect
+	}
+	r.fixture.Queries = append(r.fixture.Queries, recorded)
+}
+
+// Fixture returns a copy of the recorded queries
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fixture := &Fixture{
+		Connections: make(map[string]xqb.Dialect, len(r.fixture.Connections)),
+		Queries:     append([]RecordedQuery(nil), r.fixture.Queries...),
+	}
+	for name, dialect := range r.fixture.Connections {
+		fixture.Connections[name] = dialect
+	}
+	return fixture
+}
+
+// Replayer is an interceptor serving the recorded results without a database,
+// the statements must run in the recorded order with the recorded bindings.
+type Replayer struct {
+	t       TB
+	fixture *Fixture
+
+	mu  sync.Mutex
+	pos int
+}
+
+// NewReplayer creates a replayer of the fixture, a divergence fails the test
+func NewReplayer(t TB, fixture *Fixture) *Replayer {
+	return &Replayer{t: t, fixture: fixture}
+}
+
+// Interceptor returns the replay interceptor, it never calls the next handler
+func (r *Replayer) Interceptor() xqb.Interceptor {
+	return func(_ context.Context, event *xqb.QueryEvent, _ xqb.QueryHandler) (xqb.Result, error) {
+		recorded, err := r.next(event)
+		if err != nil {
+			return xqb.Result{}, err
+		}
+		if recorded.Error != "" {
+			return xqb.Result{}, recorded.err()
+		}
+
+		if event.Kind == xqb.QueryKindExecute {
+			return xqb.Result{Exec: replayedResult{rowsAffected: recorded.RowsAffected, lastInsertID: recorded.LastInsertID}}, nil
+		}
+
+		rows := &xqb.BufferedRows{Columns: recorded.Columns, Values: make([][]any, len(recorded.Rows))}
+		for i, row := range recorded.Rows {
+			values, err := decodeValues(row)
+			if err != nil {
+				return xqb.Result{}, err
+			}
+			rows.Values[i] = values
+		}
+		return rows.Result()
+	}
+}
+
+// next returns the recorded query matching the statement, the test fails on a divergence
+func (r *Replayer) next(event *xqb.QueryEvent) (RecordedQuery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statement := fmt.Sprintf("%s %q on %q", event.Kind, event.Sql, event.Connection)
+	if r.pos >= len(r.fixture.Queries) {
+		err := fmt.Errorf("%w: %s, the %d recorded queries already ran", ErrReplayDivergence, statement, len(r.fixture.Queries))
+		r.t.Errorf("%v", err)
+		return RecordedQuery{}, err
+	}
+
+	recorded := r.fixture.Queries[r.pos]
+	var reason string
+	switch bindings := encodeBindings(event.Bindings); {
+	case recorded.Kind != event.Kind:
+		reason = fmt.Sprintf("recorded %s, got %s", recorded.Kind, event.Kind)
+	case recorded.Connection != event.Connection:
+		reason = fmt.Sprintf("recorded connection %q, got %q", recorded.Connection, event.Connection)
+	case recorded.Sql != event.Sql:
+		reason = fmt.Sprintf("recorded sql %q", recorded.Sql)
+	case !equalValues(recorded.Bindings, bindings):
+		reason = fmt.Sprintf("recorded bindings %s, got %s", formatValues(recorded.Bindings), formatValues(bindings))
+	}
+	if reason != "" {
+		err := fmt.Errorf("%w: query %d %s: %s", ErrReplayDivergence, r.pos+1, statement, reason)
+		r.t.Errorf("%v", err)
+		return RecordedQuery{}, err
+	}
+
+	r.pos++
+	return recorded, nil
+}
+
+// ExpectationsWereMet returns an error when recorded queries didn't run
+func (r *Replayer) ExpectationsWereMet() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if left := len(r.fixture.Queries) - r.pos; left > 0 {
+		next := r.fixture.Queries[r.pos]
+		return fmt.Errorf("xqbtest: %d recorded queries didn't run, next %s %q", left, next.Kind, next.Sql)
+	}
+	return nil
+}
+
+// replayConfig holds the settings of Replay
+type replayConfig struct {
+	manager *xqb.DBM
+}
+
+// ReplayOption configures Replay
+type ReplayOption func(c *replayConfig)
+
+// ReplayWithManager records or replays the statements of the manager, xqb.DBManager() by default
+func ReplayWithManager(manager *xqb.DBM) ReplayOption {
+	return func(c *replayConfig) {
+		c.manager = manager
+	}
+}
+
+// Replay records the statements of the test into the fixture at path when the tests run with XQB_UPDATE_SNAPSHOTS=1,
+// against the connections already registered. Otherwise it replays the fixture without a database:
+// the recorded connections are registered as fake connections (on xqb.DBManager() unless ReplayWithManager is given)
+// and their transactions always succeed. The recorded errors are replayed with their message and their class,
+// they match the same sentinels (sql.ErrNoRows, xqb.ErrDeadlock...) and *xqb.DBError fields as the recorded ones.
+//
+// The interceptor is added to the settings of the manager until the test ends. The default manager uses
+// xqb.DefaultSettings() shared by every test, give a parallel test its own manager with its own settings:
+//
+//	mgr := xqb.NewManager()
+//	mgr.SetSettings(xqb.NewQueryBuilderSettings())
+//	xqbtest.Replay(t, "testdata/checkout.json", xqbtest.ReplayWithManager(mgr))
+//
+// Example:
+//
+//	func TestCheckout(t *testing.T) {
+//		xqbtest.Replay(t, "testdata/checkout.json")
+//		err := checkout.Run(ctx, cartID)
+//		...
+//	}
+func Replay(t TB, path string, options ...ReplayOption) {
+	t.Helper()
+
+	config := &replayConfig{manager: xqb.DBManager()}
+	for _, option := range options {
+		option(config)
+	}
+
+	// only the replay interceptor is removed, the interceptors added after it are kept
+	settings := config.manager.GetSettings()
+	index := len(settings.GetInterceptors())
+	t.Cleanup(func() {
+		interceptors := settings.GetInterceptors()
+		if index >= len(interceptors) {
+			return
+		}
+		settings.ClearInterceptors()
+		settings.Use(append(interceptors[:index:index], interceptors[index+1:]...)...)
+	})
+
+	if Updating() {
+		recorder := NewRecorder(config.manager)
+		settings.Use(recorder.Interceptor())
+		t.Cleanup(func() {
+			if err := recorder.Fixture().Save(path); err != nil {
+				t.Errorf("%v", err)
+			}
+		})
+		return
+	}
+
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Errorf("%v, run the test with XQB_UPDATE_SNAPSHOTS=1 to record it", err)
+		return
+	}
+
+	names := make([]string, 0, len(fixture.Connections))
+	for name := range fixture.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mock := New(t, fixture.Connections[name], WithManager(config.manager), WithName(name))
+		mock.permissive = true
+	}
+
+	replayer := NewReplayer(t, fixture)
+	settings.Use(replayer.Interceptor())
+	t.Cleanup(func() {
+		if err := replayer.ExpectationsWereMet(); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+}
+
+// replayedResult is the sql.Result of a replayed statement
+type replayedResult struct {
+	rowsAffected *int64
+	lastInsertID *int64
+}
+
+func (r replayedResult) LastInsertId() (int64, error) {
+	if r.lastInsertID == nil {
+		return 0, errors.New("xqbtest: LastInsertId wasn't recorded")
+	}
+	return *r.lastInsertID, nil
+}
+
+func (r replayedResult) RowsAffected() (int64, error) {
+	if r.rowsAffected == nil {
+		return 0, errors.New("xqbtest: RowsAffected wasn't recorded")
+	}
+	return *r.rowsAffected, nil
+}
+
+var _ sql.Result = replayedResult{}
+
+// encodeBindings encodes the bindings converted like database/sql does
+func encodeBindings(bindings []any) []Value {
+	values := make([]Value, len(bindings))
+	for i, binding := range bindings {
+		converted, err := driver.DefaultParameterConverter.ConvertValue(binding)
+		if err != nil {
+			converted = binding
+		}
+		values[i] = encodeValue(converted)
+	}
+	return values
+}
+
+func encodeValues(row []any) []Value {
+	values := make([]Value, len(row))
+	for i, value := range row {
+		values[i] = encodeValue(value)
+	}
+	return values
+}
+
+func encodeValue(value any) Value {
+	var (
+		kind    string
+		encoded any
+	)
+	switch v := value.(type) {
+	case nil:
+		return Value{Type: "null"}
+	case int64:
+		kind, encoded = "int64", v
+	case float64:
+		kind, encoded = "float64", v
+	case bool:
+		kind, encoded = "bool", v
+	case string:
+		kind, encoded = "string", v
+	case []byte:
+		if utf8.Valid(v) {
+			kind, encoded = "bytes", string(v)
+		} else {
+			kind, encoded = "base64", v // encoding/json writes []byte in base64
+		}
+	case time.Time:
+		kind, encoded = "time", v.Format(time.RFC3339Nano)
+	default:
+		kind, encoded = "other", fmt.Sprintf("%v", v)
+	}
+
+	data, _ := json.Marshal(encoded)
+	return Value{Type: kind, Value: data}
+}
+
+func decodeValues(row []Value) ([]any, error) {
+	values := make([]any, len(row))
+	for i, value := range row {
+		decoded, err := value.decode()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = decoded
+	}
+	return values, nil
+}
+
+// decode returns the driver value of the recorded value
+func (v Value) decode() (any, error) {
+	var (
+		decoded any
+		err     error
+	)
+	switch v.Type {
+	case "null":
+		return nil, nil
+	case "int64":
+		decoded, err = unmarshalValue[int64](v.Value)
+	case "float64":
+		decoded, err = unmarshalValue[float64](v.Value)
+	case "bool":
+		decoded, err = unmarshalValue[bool](v.Value)
+	case "string", "other":
+		decoded, err = unmarshalValue[string](v.Value)
+	case "bytes":
+		var s string
+		s, err = unmarshalValue[string](v.Value)
+		decoded = []byte(s)
+	case "base64":
+		decoded, err = unmarshalValue[[]byte](v.Value)
+	case "time":
+		var s string
+		if s, err = unmarshalValue[string](v.Value); err == nil {
+			decoded, err = time.Parse(time.RFC3339Nano, s)
+		}
+	default:
+		err = fmt.Errorf("unknown type %q", v.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("xqbtest: failed to decode the recorded value %s: %w", v.Value, err)
+	}
+	return decoded, nil
+}
+
+func unmarshalValue[T any](data json.RawMessage) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+func equalValues(a, b []Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || !bytes.Equal(compactJSON(a[i].Value), compactJSON(b[i].Value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func compactJSON(data json.RawMessage) []byte {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data); err != nil {
+		return data
+	}
+	return compacted.Bytes()
+}
+
+func formatValues(values []Value) string {
+	var formatted bytes.Buffer
+	formatted.WriteString("[")
+	for i, value := range values {
+		if i > 0 {
+			formatted.WriteString(", ")
+		}
+		if value.Type == "null" {
+			formatted.WriteString("null")
+			continue
+		}
+		formatted.Write(value.Value)
+	}
+	formatted.WriteString("]")
+	return formatted.String()
+}
@@ -0,0 +1,168 @@
+package xqbtest_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/iMohamedSheta/xqb/xqbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// runCheckout runs the statements recorded and replayed by the tests
+func runCheckout(mgr *xqb.DBM, settings *xqb.QueryBuilderSettings) ([]map[string]any, int64, error) {
+	users, err := mgr.Table("users").WithSettings(settings).Where("id", "=", 1).Get()
+	if err != nil {
+		return nil, 0, err
+	}
+	affected, err := mgr.Table("carts").WithSettings(settings).Where("user_id", "=", 1).Update(map[string]any{"paid": true})
+	if err != nil {
+		return nil, 0, err
+	}
+	_, err = mgr.Table("sessions").WithSettings(settings).Where("user_id", "=", 1).Delete()
+	return users, affected, err
+}
+
+// recordCheckout records runCheckout against a mock standing for the database
+func recordCheckout(t *testing.T) *xqbtest.Fixture {
+	created := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	mock := xqbtest.New(t, xqb.DialectMySql)
+	mock.ExpectQuery("SELECT * FROM `users` WHERE `id` = ?").WithArgs(1).
+		WillReturnRows(xqbtest.NewRows("id", "name", "score", "created_at", "deleted_at").AddRow(1, []byte("Ada"), 9.5, created, nil))
+	mock.ExpectExec("UPDATE `carts` SET `paid` = ? WHERE `user_id` = ?").WithArgs(true, 1).WillReturnResult(0, 2)
+	mock.ExpectExec("DELETE FROM `sessions` WHERE `user_id` = ?").WillReturnError(errors.New("lock wait timeout"))
+
+	recorder := xqbtest.NewRecorder(mock.Manager())
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(recorder.Interceptor())
+
+	users, affected, err := runCheckout(mock.Manager(), settings)
+	assert.ErrorContains(t, err, "lock wait timeout")
+	assert.Equal(t, int64(2), affected)
+	assert.Equal(t, []map[string]any{{"id": int64(1), "name": "Ada", "score": 9.5, "created_at": created, "deleted_at": nil}}, users)
+
+	return recorder.Fixture()
+}
+
+func Test_Replay_ServesTheRecordedResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkout.json")
+	assert.NoError(t, recordCheckout(t).Save(path))
+
+	fixture, err := xqbtest.LoadFixture(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]xqb.Dialect{"default": xqb.DialectMySql}, fixture.Connections)
+	assert.Len(t, fixture.Queries, 3)
+
+	// no expectation: the statements never reach the database
+	mock := xqbtest.New(t, xqb.DialectMySql)
+	replayer := xqbtest.NewReplayer(t, fixture)
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(replayer.Interceptor())
+
+	users, affected, err := runCheckout(mock.Manager(), settings)
+
+	assert.ErrorContains(t, err, "lock wait timeout")
+	assert.Equal(t, int64(2), affected)
+	assert.Equal(t, []map[string]any{{
+		"id":         int64(1),
+		"name":       "Ada",
+		"score":      9.5,
+		"created_at": time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC),
+		"deleted_at": nil,
+	}}, users)
+	assert.NoError(t, replayer.ExpectationsWereMet())
+}
+
+func Test_Replay_FailsOnDivergence(t *testing.T) {
+	fixture := recordCheckout(t)
+
+	rec := &recorderTB{}
+	mock := xqbtest.New(t, xqb.DialectMySql)
+	replayer := xqbtest.NewReplayer(rec, fixture)
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(replayer.Interceptor())
+
+	_, err := mock.Manager().Table("users").WithSettings(settings).Where("id", "=", 2).Get()
+
+	assert.ErrorIs(t, err, xqbtest.ErrReplayDivergence)
+	assert.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], "recorded bindings [1], got [2]")
+	assert.ErrorContains(t, replayer.ExpectationsWereMet(), "3 recorded queries didn't run")
+}
+
+func Test_Replay_RegistersTheRecordedConnections(t *testing.T) {
+	if xqbtest.Updating() {
+		t.Skip("Replay records when updating")
+	}
+
+	mock := xqbtest.New(t, xqb.DialectPostgres, xqbtest.WithName("billing"))
+	recorder := xqbtest.NewRecorder(mock.Manager())
+	mock.ExpectExec(`DELETE FROM "invoices" WHERE "id" = $1`).WillReturnResult(0, 1)
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(recorder.Interceptor())
+	_, err := mock.Manager().Sql(`DELETE FROM "invoices" WHERE "id" = $1`, 7).WithSettings(settings).Connection("billing").Execute()
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "billing.json")
+	assert.NoError(t, recorder.Fixture().Save(path))
+
+	mgr := xqb.NewManager()
+	mgr.SetSettings(xqb.NewQueryBuilderSettings())
+	xqbtest.Replay(t, path, xqbtest.ReplayWithManager(mgr))
+	assert.Len(t, mgr.GetSettings().GetInterceptors(), 1)
+	assert.Empty(t, xqb.DefaultSettings().GetInterceptors(), "the manager settings hold the replay interceptor")
+
+	err = mgr.TransactionCtxOn(context.Background(), "billing", nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := mgr.Sql(`DELETE FROM "invoices" WHERE "id" = $1`, 7).WithContext(ctx).Connection("billing").Execute()
+		return err
+	})
+	assert.NoError(t, err)
+
+	dialect, err := mgr.GetDialect("billing")
+	assert.NoError(t, err)
+	assert.Equal(t, xqb.DialectPostgres, dialect)
+}
+
+func Test_Replay_RebuildsTypedErrors(t *testing.T) {
+	deadlock := &xqb.DBError{Kind: xqb.ErrDeadlock, Number: 1213, SQLState: "40001", Table: "carts", Err: errors.New("Error 1213: Deadlock found")}
+	mock := xqbtest.New(t, xqb.DialectMySql)
+	mock.ExpectExec("UPDATE `carts` SET `paid` = ?").WillReturnError(deadlock)
+	mock.ExpectExec("DELETE FROM `sessions`").WillReturnError(fmt.Errorf("session cleanup: %w", sql.ErrConnDone))
+
+	recorder := xqbtest.NewRecorder(mock.Manager())
+	settings := xqb.NewQueryBuilderSettings()
+	settings.Use(recorder.Interceptor())
+	run := func(mgr *xqb.DBM, settings *xqb.QueryBuilderSettings) (error, error) {
+		_, updateErr := mgr.Sql("UPDATE `carts` SET `paid` = ?", true).WithSettings(settings).Execute()
+		_, deleteErr := mgr.Sql("DELETE FROM `sessions`").WithSettings(settings).Execute()
+		return updateErr, deleteErr
+	}
+	_, _ = run(mock.Manager(), settings)
+
+	path := filepath.Join(t.TempDir(), "errors.json")
+	assert.NoError(t, recorder.Fixture().Save(path))
+	fixture, err := xqbtest.LoadFixture(path)
+	assert.NoError(t, err)
+
+	replayer := xqbtest.NewReplayer(t, fixture)
+	settings = xqb.NewQueryBuilderSettings()
+	settings.Use(replayer.Interceptor())
+	updateErr, deleteErr := run(xqbtest.New(t, xqb.DialectMySql).Manager(), settings)
+
+	var dbErr *xqb.DBError
+	assert.ErrorIs(t, updateErr, xqb.ErrDeadlock)
+	assert.ErrorAs(t, updateErr, &dbErr)
+	assert.Equal(t, 1213, dbErr.Number)
+	assert.Equal(t, "40001", dbErr.SQLState)
+	assert.Equal(t, "carts", dbErr.Table)
+	assert.EqualError(t, updateErr, "Error 1213: Deadlock found")
+
+	assert.ErrorIs(t, deleteErr, sql.ErrConnDone)
+	assert.EqualError(t, deleteErr, "session cleanup: sql: connection is already closed")
+	assert.NoError(t, replayer.ExpectationsWereMet())
+}
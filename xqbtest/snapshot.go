@@ -0,0 +1,178 @@
+package xqbtest
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/iMohamedSheta/xqb/dialects"
+)
+
+// UpdateEnv is the environment variable regenerating the golden files and the replay fixtures:
+// XQB_UPDATE_SNAPSHOTS=1 go test ./...
+const UpdateEnv = "XQB_UPDATE_SNAPSHOTS"
+
+// init registers the -update flag (go test -update) unless a package initialized before xqbtest defined it
+func init() {
+	if flag.Lookup("update") == nil {
+		flag.Bool("update", false, "regenerate the xqbtest golden files and replay fixtures")
+	}
+}
+
+// Updating reports whether the golden files and the replay fixtures are regenerated, set with UpdateEnv or
+// with the -update flag (go test -update). The flag is registered by xqbtest, test packages importing it
+// must not define their own -update flag.
+func Updating() bool {
+	if update, err := strconv.ParseBool(os.Getenv(UpdateEnv)); err == nil && update {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		update, _ := strconv.ParseBool(f.Value.String())
+		return update
+	}
+	return false
+}
+
+// SnapshotDir is the directory of the golden files, relative to the package of the test
+var SnapshotDir = "testdata"
+
+// SnapshotTB is the part of testing.TB used by AssertSnapshot
+type SnapshotTB interface {
+	TB
+	Name() string
+}
+
+var (
+	snapshotCallsMu sync.Mutex
+	snapshotCalls   = make(map[SnapshotTB]int)
+)
+
+// snapshotManager creates the builders of Table, its connection has no database
+var snapshotManager = sync.OnceValue(func() *xqb.DBM {
+	manager := xqb.NewManager()
+	_ = manager.SetConnection(&xqb.Connection{Name: "default", Dialect: xqb.DialectMySql})
+	return manager
+})
+
+// Table returns a builder of the table for snapshot tests without a connection, it can't execute queries
+func Table(table string) *xqb.QueryBuilder {
+	return snapshotManager().Table(table)
+}
+
+// AssertSnapshot compiles the builder for every dialect (see dialects.Dialects) and compares the sql and the
+// bindings with the golden file testdata/<test name>.golden, the following snapshots of the same test are
+// suffixed _2, _3... Run the tests with -update or XQB_UPDATE_SNAPSHOTS=1 to write the golden files (see Updating).
+//
+// Example:
+//
+//	xqbtest.AssertSnapshot(t, xqbtest.Table("users").Where("active", "=", true).OrderBy("id", "DESC"))
+func AssertSnapshot(t SnapshotTB, qb *xqb.QueryBuilder) {
+	t.Helper()
+
+	snapshotCallsMu.Lock()
+	if _, ok := snapshotCalls[t]; !ok {
+		t.Cleanup(func() {
+			snapshotCallsMu.Lock()
+			delete(snapshotCalls, t)
+			snapshotCallsMu.Unlock()
+		})
+	}
+	snapshotCalls[t]++
+	call := snapshotCalls[t]
+	snapshotCallsMu.Unlock()
+
+	name := t.Name()
+	if call > 1 {
+		name = fmt.Sprintf("%s_%d", name, call)
+	}
+	AssertNamedSnapshot(t, name, qb)
+}
+
+// AssertNamedSnapshot is AssertSnapshot with the golden file testdata/<name>.golden
+func AssertNamedSnapshot(t TB, name string, qb *xqb.QueryBuilder) {
+	t.Helper()
+
+	actual := Snapshot(qb)
+	path := filepath.Join(SnapshotDir, snapshotFileName(name)+".golden")
+
+	if Updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("xqbtest: failed to create the snapshot directory: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(actual), 0o644); err != nil {
+			t.Errorf("xqbtest: failed to write the snapshot %s: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("xqbtest: snapshot %s doesn't exist, run the test with XQB_UPDATE_SNAPSHOTS=1 or -update to create it:\n%s", path, actual)
+		return
+	}
+	if err != nil {
+		t.Errorf("xqbtest: failed to read the snapshot %s: %v", path, err)
+		return
+	}
+
+	if string(expected) != actual {
+		t.Errorf("xqbtest: snapshot %s doesn't match, run the test with XQB_UPDATE_SNAPSHOTS=1 or -update to accept the change\n--- expected\n%s\n--- actual\n%s",
+			path, expected, actual)
+	}
+}
+
+// Snapshot returns the sql and the bindings of the builder compiled for every dialect in the golden file format,
+// the builder dialect is restored afterwards.
+func Snapshot(qb *xqb.QueryBuilder) string {
+	if original := qb.GetDialect(); original != nil {
+		defer qb.SetDialect(original.Getdialect())
+	}
+
+	var snapshot strings.Builder
+	for i, dialect := range dialects.Dialects() {
+		if i > 0 {
+			snapshot.WriteString("\n")
+		}
+		fmt.Fprintf(&snapshot, "-- %s --\n", dialect)
+
+		sql, bindings, err := qb.SetDialect(dialect).ToSql()
+		if err != nil {
+			fmt.Fprintf(&snapshot, "error: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(&snapshot, "%s\nbindings: %s\n", sql, formatBindings(bindings))
+	}
+
+	return snapshot.String()
+}
+
+// formatBindings renders the bindings as JSON, the values JSON can't encode are rendered with %v
+func formatBindings(bindings []any) string {
+	values := make([]string, len(bindings))
+	for i, binding := range bindings {
+		encoded, err := json.Marshal(binding)
+		if err != nil {
+			values[i] = fmt.Sprintf("%v", binding)
+			continue
+		}
+		values[i] = string(encoded)
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// snapshotFileName turns the test name into a file name, subtests use their full path joined with _
+func snapshotFileName(name string) string {
+	return unsafeFileChars.ReplaceAllString(name, "_")
+}
@@ -0,0 +1,86 @@
+package xqbtest_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iMohamedSheta/xqb"
+	"github.com/iMohamedSheta/xqb/xqbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Snapshot_CompilesEveryDialect(t *testing.T) {
+	xqbtest.AssertSnapshot(t, xqbtest.Table("users").
+		Select("id", "name").
+		Where("active", "=", true).
+		WhereIn("role", []any{"admin", "editor"}).
+		OrderBy("id", "DESC").
+		Limit(10))
+
+	// the second snapshot of the test is suffixed _2
+	xqbtest.AssertSnapshot(t, xqbtest.Table("posts").Join("users", "users.id = posts.user_id").Where("posts.id", ">", 5))
+}
+
+func Test_Snapshot_Subtests(t *testing.T) {
+	t.Run("by email", func(t *testing.T) {
+		xqbtest.AssertSnapshot(t, xqbtest.Table("users").Where("email", "=", "a@example.com"))
+	})
+}
+
+func Test_Snapshot_RestoresTheBuilderDialect(t *testing.T) {
+	mock := xqbtest.New(t, xqb.DialectPostgres)
+	qb := mock.Manager().Table("users").Where("id", "=", 1)
+
+	snapshot := xqbtest.Snapshot(qb)
+
+	assert.Contains(t, snapshot, "-- mysql --\nSELECT * FROM `users` WHERE `id` = ?\nbindings: [1]\n")
+	assert.Contains(t, snapshot, "-- postgres --\nSELECT * FROM \"users\" WHERE \"id\" = $1\nbindings: [1]\n")
+
+	sql, _, err := qb.ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "users" WHERE "id" = $1`, sql)
+}
+
+func Test_Snapshot_ReportsMismatchAndMissingFiles(t *testing.T) {
+	if xqbtest.Updating() {
+		t.Skip("the golden files are being updated")
+	}
+
+	dir := t.TempDir()
+	previous := xqbtest.SnapshotDir
+	xqbtest.SnapshotDir = dir
+	t.Cleanup(func() {
+		xqbtest.SnapshotDir = previous
+	})
+	golden := xqbtest.Snapshot(xqbtest.Table("users").Where("email", "=", "a@example.com"))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "by_email.golden"), []byte(golden), 0o644))
+
+	rec := &recorderTB{}
+	xqbtest.AssertNamedSnapshot(rec, "by email", xqbtest.Table("users").Where("email", "=", "a@example.com"))
+	assert.Empty(t, rec.errors)
+
+	xqbtest.AssertNamedSnapshot(rec, "by email", xqbtest.Table("users").Where("email", "=", "b@example.com"))
+	xqbtest.AssertNamedSnapshot(rec, "missing", xqbtest.Table("users"))
+
+	assert.Len(t, rec.errors, 2)
+	assert.Contains(t, rec.errors[0], "doesn't match")
+	assert.Contains(t, rec.errors[0], `bindings: ["b@example.com"]`)
+	assert.Contains(t, rec.errors[1], "doesn't exist, run the test with XQB_UPDATE_SNAPSHOTS=1")
+}
+
+func Test_Snapshot_UpdatingFromEnv(t *testing.T) {
+	t.Setenv(xqbtest.UpdateEnv, "1")
+	assert.True(t, xqbtest.Updating())
+
+	t.Setenv(xqbtest.UpdateEnv, "0")
+	assert.Equal(t, flag.Lookup("update").Value.String() == "true", xqbtest.Updating())
+}
+
+func Test_Snapshot_RegistersTheUpdateFlag(t *testing.T) {
+	f := flag.Lookup("update")
+	if assert.NotNil(t, f) {
+		assert.Equal(t, "false", f.DefValue)
+	}
+}